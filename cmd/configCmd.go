@@ -0,0 +1,273 @@
+package cmd
+
+import (
+	"divekit-cli/divekit/ars"
+	"divekit-cli/divekit/origin"
+	"divekit-cli/divekit/policy"
+	"divekit-cli/utils"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+var (
+	// Flags
+	ConfigFileFlag   []string
+	ConfigModeFlag   string
+	ConfigStrictFlag bool
+
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Check the repositoryConfig.json files of the distributions",
+	}
+
+	configCheckCmd = &cobra.Command{
+		Use:   "check [distribution...]",
+		Short: "List the safety rules, and check distributions against them (default: all distributions)",
+		Long: `List the safety rules that are evaluated before each patch and distribute run, with their severity:
+warn (only logged), confirm (you have to confirm the run), or block (the run is aborted). The severities can
+be changed in the "policy" section of .divekit_norepo/cli-settings.json; a single run can accept the risk of
+a rule with --accept-risk=<rule>. Then the given distributions are checked as for distribute.`,
+		PersistentPreRun: originRepoPersistentPreRun,
+		Run:              configCheckRun,
+	}
+
+	configValidateCmd = &cobra.Command{
+		Use:   "validate [distribution...]",
+		Short: "Validate the repositoryConfig.json of distributions (default: all distributions of the origin repo)",
+		Long: `Strictly validate repositoryConfig.json files: syntax errors, unknown keys, values of the wrong type,
+missing required keys, and inconsistent combinations (e.g. a repositoryCount that doesn't match the
+repositoryMembers, or GitLab group IDs of 0 in remote mode) are reported with their line and column.
+The same validation runs automatically before patch and distribute.`,
+		PersistentPreRun: configValidatePersistentPreRun,
+		PreRun:           configValidatePreRun,
+		Run:              configValidateRun,
+	}
+)
+
+// the validation modes, by their flag values
+var configValidationModes = map[string]ars.ValidationMode{
+	"configured": ars.ValidateAsConfigured,
+	"local":      ars.ValidateForLocalMode,
+	"remote":     ars.ValidateForRemoteMode,
+}
+
+func init() {
+	log.Debug("config.init()")
+	configValidateCmd.Flags().StringSliceVarP(&ConfigFileFlag, "file", "f", []string{},
+		"validate these files instead of the distributions of the origin repo")
+	configValidateCmd.Flags().StringVar(&ConfigModeFlag, "mode", "remote",
+		"validate for \"remote\" mode (as in distribute), \"local\" mode (as in patch), "+
+			"or as \"configured\" by general.localMode")
+	configValidateCmd.Flags().BoolVar(&ConfigStrictFlag, "strict", false, "treat warnings as errors")
+	configCmd.AddCommand(configValidateCmd, configCheckCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// Standalone files can be validated without an origin repo
+func configValidatePersistentPreRun(cmd *cobra.Command, args []string) {
+	if len(ConfigFileFlag) > 0 {
+		persistentPreRun(cmd, args)
+		return
+	}
+	originRepoPersistentPreRun(cmd, args)
+}
+
+func configValidatePreRun(cmd *cobra.Command, args []string) {
+	log.Debug("config.validatePreRun()")
+	if _, ok := configValidationModes[ConfigModeFlag]; !ok {
+		utils.AbortWithError(utils.ErrUsage, "--mode must be one of remote, local, or configured, not %s",
+			ConfigModeFlag)
+	}
+	if len(ConfigFileFlag) > 0 && len(args) > 0 {
+		utils.AbortWithError(utils.ErrUsage, "Please give either distributions or --file, not both")
+	}
+}
+
+func configValidateRun(cmd *cobra.Command, args []string) {
+	log.Debug("config.validate()")
+	repositoryConfigFiles := []*ars.RepositoryConfigFileType{}
+	for _, filePath := range ConfigFileFlag {
+		repositoryConfigFiles = append(repositoryConfigFiles, &ars.RepositoryConfigFileType{FilePath: filePath})
+	}
+	if len(ConfigFileFlag) == 0 {
+		distributionNames := args
+		if len(distributionNames) == 0 {
+			distributionNames = origin.OriginRepo.DistributionNames()
+		}
+		for _, distributionName := range distributionNames {
+			repositoryConfigFiles = append(repositoryConfigFiles, getDistributionOrFail(distributionName).RepositoryConfigFile)
+		}
+	}
+
+	failed := false
+	for _, repositoryConfigFile := range repositoryConfigFiles {
+		issues, err := repositoryConfigFile.Validate(configValidationModes[ConfigModeFlag])
+		if err != nil {
+			fmt.Printf("%s: error: %v\n", repositoryConfigFile.FilePath, err)
+			failed = true
+			continue
+		}
+		for _, issue := range issues {
+			fmt.Println(issue.String())
+		}
+		if ars.HasValidationErrors(issues) || (ConfigStrictFlag && len(issues) > 0) {
+			failed = true
+		} else if len(issues) == 0 {
+			fmt.Printf("%s: ok\n", repositoryConfigFile.FilePath)
+		}
+	}
+	if failed {
+		utils.Exit(utils.ErrInvalidConfig.ExitCode)
+	}
+}
+
+// Validates the repositoryConfig.json of a distribution before it is used: warnings are logged, and
+// errors abort the command
+func validateRepositoryConfigOrFail(repositoryConfigFile *ars.RepositoryConfigFileType, mode ars.ValidationMode) {
+	log.Debug("config.validateRepositoryConfigOrFail()")
+	issues, err := repositoryConfigFile.Validate(mode)
+	utils.OutputAndAbortIfError(err)
+	logValidationIssuesOrFail(issues)
+}
+
+// Validates the content of the config before it is written, and aborts without writing it if it has errors
+func writeRepositoryConfigIfValid(repositoryConfigFile *ars.RepositoryConfigFileType, mode ars.ValidationMode) {
+	log.Debug("config.writeRepositoryConfigIfValid()")
+	issues, err := repositoryConfigFile.ValidateContent(mode)
+	utils.OutputAndAbortIfError(err)
+	logValidationIssuesOrFail(issues)
+	utils.OutputAndAbortIfError(repositoryConfigFile.WriteContent())
+}
+
+// Logs the issues, and aborts if any of them is an error
+func logValidationIssuesOrFail(issues []ars.ValidationIssue) {
+	for _, issue := range issues {
+		if issue.Severity == ars.ValidationError {
+			log.Error(issue.String())
+		} else {
+			log.Warn(issue.String())
+		}
+	}
+	if ars.HasValidationErrors(issues) {
+		utils.AbortWithError(utils.ErrInvalidConfig,
+			"The config is invalid, see above - you can check it with 'divekit config validate'")
+	}
+}
+
+func configCheckRun(cmd *cobra.Command, args []string) {
+	log.Debug("config.check()")
+	settings := origin.OriginRepo.CLISettingsFile.Content.Policy
+	utils.OutputAndAbortIfError(policy.ValidateSettings(settings, AcceptRiskFlag))
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "RULE\tSEVERITY\tDESCRIPTION")
+	for _, rule := range policy.Rules() {
+		severity := rule.EffectiveSeverity(settings)
+		if containsString(AcceptRiskFlag, rule.Name) {
+			severity += " (accepted)"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", rule.Name, severity, rule.Description)
+	}
+	writer.Flush()
+	fmt.Println()
+
+	distributionNames := args
+	if len(distributionNames) == 0 {
+		distributionNames = origin.OriginRepo.DistributionNames()
+	}
+	blocked := false
+	for _, distributionName := range distributionNames {
+		// checked as distribute would run it, i.e. in remote mode
+		repositoryConfigFile := readDistributionConfig(distributionName).Clone()
+		repositoryConfigFile.Content.General.LocalMode = false
+		violations := policy.Evaluate(newPolicyRunContext("distribute", distributionName, repositoryConfigFile),
+			AcceptRiskFlag)
+		if len(violations) == 0 {
+			fmt.Printf("%s: ok\n", distributionName)
+		}
+		for _, violation := range violations {
+			severity := violation.Severity
+			if violation.Accepted {
+				severity = "accepted"
+			} else if severity == policy.SeverityBlock {
+				blocked = true
+			}
+			fmt.Printf("%s: [%s] %s: %s\n", distributionName, severity, violation.Rule.Name, violation.Message)
+		}
+	}
+	if blocked {
+		utils.Exit(utils.ErrBlocked.ExitCode)
+	}
+}
+
+// Collects what the safety rules need to know about a run. repositoryConfigFile is the config with the
+// values the tools will run with.
+func newPolicyRunContext(command, distributionName string,
+	repositoryConfigFile *ars.RepositoryConfigFileType) *policy.RunContextType {
+	log.Debug("config.newPolicyRunContext()")
+	run := &policy.RunContextType{
+		Command:            command,
+		DistributionName:   distributionName,
+		RepositoryConfig:   repositoryConfigFile,
+		OtherDistributions: make(map[string]*ars.RepositoryConfigFileType),
+		Settings:           origin.OriginRepo.CLISettingsFile.Content.Policy,
+	}
+	if command == "patch" {
+		run.PatchFiles = PatchFiles
+		run.PatchTarget = PatchTargetFlag
+	}
+	for _, otherName := range origin.OriginRepo.DistributionNames() {
+		if otherName == distributionName {
+			continue
+		}
+		otherConfigFile := &ars.RepositoryConfigFileType{
+			FilePath: origin.OriginRepo.GetDistribution(otherName).RepositoryConfigFile.FilePath,
+		}
+		if err := otherConfigFile.ParseContent(); err != nil {
+			log.Warnf("Ignoring distribution %s in the safety checks: %v", otherName, err)
+			continue
+		}
+		run.OtherDistributions[otherName] = otherConfigFile
+	}
+	return run
+}
+
+// Evaluates the safety rules before a run: warnings are logged, violations of "confirm" rules have to be
+// confirmed, and violations of "block" rules abort the run - unless their risk is accepted with --accept-risk
+func enforcePolicies(run *policy.RunContextType) {
+	log.Debug("config.enforcePolicies()")
+	utils.OutputAndAbortIfError(policy.ValidateSettings(run.Settings, AcceptRiskFlag))
+	toConfirm := []string{}
+	blockingRules := []string{}
+	for _, violation := range policy.Evaluate(run, AcceptRiskFlag) {
+		message := violation.Rule.Name + ": " + violation.Message
+		switch {
+		case violation.Accepted:
+			log.Warn("Accepted risk " + message)
+		case violation.Severity == policy.SeverityWarn:
+			log.Warn(message)
+		case violation.Severity == policy.SeverityConfirm:
+			toConfirm = append(toConfirm, message)
+		case violation.Severity == policy.SeverityBlock:
+			log.Error(message)
+			if !containsString(blockingRules, violation.Rule.Name) {
+				blockingRules = append(blockingRules, violation.Rule.Name)
+			}
+		}
+	}
+	if len(blockingRules) > 0 {
+		utils.AbortWithError(utils.ErrBlocked,
+			"Blocked by the safety rule(s) above - if you are sure, run again with --accept-risk=%s",
+			strings.Join(blockingRules, ","))
+	}
+	if len(toConfirm) > 0 {
+		utils.RequireConfirmations(utils.ConfirmationType{
+			Action: fmt.Sprintf("Run %s for distribution %s despite these safety findings:",
+				run.Command, run.DistributionName),
+			Details: append(toConfirm, repositoryConfigDetails(run.RepositoryConfig)...),
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"divekit-cli/divekit/ars"
+	"divekit-cli/divekit/origin"
+	"divekit-cli/utils"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	// command state vars
+	DistributeDistribution *origin.Distribution
+
+	distributeCmd = &cobra.Command{
+		Use:   "distribute <distribution>",
+		Short: "Create the individualized repos for a distribution",
+		Long: `Run the ARS in remote mode to create the individualized code and test repos for all members
+of a certain distribution of the origin repo. The generated individualization and overview files
+are copied back into the distribution folder afterwards.`,
+		Args:   cobra.ExactArgs(1),
+		PreRun: distributePreRun,
+		Run:    distributeRun,
+	}
+)
+
+func init() {
+	log.Debug("distribute.init()")
+	addTimeoutFlag(distributeCmd)
+	rootCmd.AddCommand(distributeCmd)
+}
+
+// Checks preconditions before running the command
+func distributePreRun(cmd *cobra.Command, args []string) {
+	log.Debug("distribute.preRun()")
+	if origin.OriginRepo == nil {
+		utils.AbortWithError(utils.ErrUsage, "You need to specify an origin repo with -o / --originrepo")
+	}
+	utils.OutputAndAbortIfError(utils.ParsePhaseTimeouts())
+	startPhase("check")
+	var err error
+	ARSRepo, err = ars.NewARSRepo()
+	utils.OutputAndAbortIfError(err)
+
+	DistributeDistribution = getDistributionOrFail(args[0])
+}
+
+func distributeRun(cmd *cobra.Command, args []string) {
+	log.Debug("distribute.run()")
+	startPhase("prepare")
+	if !InPlaceFlag {
+		// the results are copied into the distribution at the end, which must not fail on the lock after the
+		// repos have been created
+		acquireRunLock("distribute")
+	}
+	prepareToolRepos("distribute")
+	repositoryConfigWithinARSRepo := cloneRepositoryConfigIntoARSRepo(DistributeDistribution, ars.ValidateForRemoteMode)
+	repositoryConfigWithinARSRepo.Content.General.LocalMode = false
+	enforcePolicies(newPolicyRunContext("distribute", args[0], repositoryConfigWithinARSRepo))
+	if err := repositoryConfigWithinARSRepo.WriteContent(); err != nil {
+		utils.AbortWithError(utils.ErrIO, "Error writing repositoryConfig.json within the ARS repo: %v", err)
+	}
+	if DistributeDistribution.IndividualizationConfigFileName != "" {
+		copySavedIndividualizationFileToARS(DistributeDistribution)
+	}
+
+	individualizationFilesBefore, overviewFilesBefore := snapshotARSOutputDirs()
+	if !utils.DryRunFlag {
+		utils.RequireConfirmations(utils.ConfirmationType{
+			Action:  fmt.Sprintf("Create the repositories of distribution '%s' on GitLab", args[0]),
+			Details: repositoryConfigDetails(repositoryConfigWithinARSRepo),
+		})
+	}
+	startPhase("distribute")
+	err := utils.RunTool(utils.ToolARS, ARSRepo.RepoDir,
+		"Creating the individualized repositories for distribution "+args[0])
+	if err != nil {
+		utils.OutputAndAbortIfError(fmt.Errorf("Error creating the repositories: %w", err))
+	}
+	if utils.DryRunFlag {
+		return
+	}
+
+	startPhase("copy results")
+	copyGeneratedFilesToDistribution(individualizationFilesBefore, overviewFilesBefore)
+}
+
+func snapshotARSOutputDirs() (map[string]time.Time, map[string]time.Time) {
+	log.Debug("distribute.snapshotARSOutputDirs()")
+	individualizationFiles, err := utils.ListFileModTimes(ARSRepo.IndividualizationConfig.Dir)
+	utils.OutputAndAbortIfError(err)
+	overviewFiles, err := utils.ListFileModTimes(ARSRepo.GeneratedOverviewFiles.Dir)
+	utils.OutputAndAbortIfError(err)
+	return individualizationFiles, overviewFiles
+}
+
+// Copies the individual_repositories_*.json and overview files written by the ARS run back into the
+// distribution folder. A previous individual_repositories file is moved to an "archive" subfolder, so
+// that the distribution keeps exactly one of them.
+func copyGeneratedFilesToDistribution(individualizationFilesBefore, overviewFilesBefore map[string]time.Time) {
+	log.Debug("distribute.copyGeneratedFilesToDistribution()")
+	newIndividualizationFiles, err :=
+		utils.FindNewOrChangedFiles(ARSRepo.IndividualizationConfig.Dir, individualizationFilesBefore)
+	utils.OutputAndAbortIfError(err)
+	newIndividualizationFiles = filterByPrefix(newIndividualizationFiles, "individual_repositories")
+	if len(newIndividualizationFiles) > 1 {
+		utils.AbortWithError(utils.ErrAmbiguous,
+			"The ARS generated several individual_repositories files, don't know which one to keep:\n%s",
+			strings.Join(newIndividualizationFiles, "\n"))
+	}
+	if len(newIndividualizationFiles) == 1 {
+		archiveIndividualizationFile(newIndividualizationFiles[0])
+		err = utils.CopyFile(newIndividualizationFiles[0], DistributeDistribution.Dir)
+		utils.OutputAndAbortIfError(err)
+		DistributeDistribution.IndividualizationConfigFileName =
+			filepath.Join(DistributeDistribution.Dir, filepath.Base(newIndividualizationFiles[0]))
+		log.Info("Saved individualization file " + DistributeDistribution.IndividualizationConfigFileName)
+	}
+
+	newOverviewFiles, err := utils.FindNewOrChangedFiles(ARSRepo.GeneratedOverviewFiles.Dir, overviewFilesBefore)
+	utils.OutputAndAbortIfError(err)
+	for _, overviewFile := range newOverviewFiles {
+		err = utils.CopyFile(overviewFile, DistributeDistribution.Dir)
+		utils.OutputAndAbortIfError(err)
+		log.Info("Saved overview file " + filepath.Join(DistributeDistribution.Dir, filepath.Base(overviewFile)))
+	}
+}
+
+func archiveIndividualizationFile(newIndividualizationFile string) {
+	log.Debug("distribute.archiveIndividualizationFile()")
+	oldFile := DistributeDistribution.IndividualizationConfigFileName
+	if oldFile == "" || filepath.Base(oldFile) == filepath.Base(newIndividualizationFile) {
+		return
+	}
+	archiveDir := filepath.Join(DistributeDistribution.Dir, "archive")
+	err := os.MkdirAll(archiveDir, 0755)
+	if err == nil {
+		err = os.Rename(oldFile, filepath.Join(archiveDir, filepath.Base(oldFile)))
+	}
+	utils.OutputAndAbortIfError(err)
+	log.Info("Moved previous individualization file to " + archiveDir)
+}
+
+func filterByPrefix(filePaths []string, prefix string) []string {
+	filtered := []string{}
+	for _, filePath := range filePaths {
+		if strings.HasPrefix(filepath.Base(filePath), prefix) {
+			filtered = append(filtered, filePath)
+		}
+	}
+	return filtered
+}
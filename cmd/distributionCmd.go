@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"divekit-cli/divekit/ars"
+	"divekit-cli/divekit/origin"
+	"divekit-cli/utils"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+)
+
+var (
+	// Flags
+	DistributionFromFlag                  string
+	DistributionWithIndividualizationFlag bool
+
+	distributionCmd = &cobra.Command{
+		Use:   "distribution",
+		Short: "Manage the distributions of the origin repo",
+		Long: `List, show, create, copy, and delete the distributions of the origin repo, i.e. the folders in
+.divekit_norepo/distributions`,
+		PersistentPreRun: originRepoPersistentPreRun,
+	}
+
+	distributionListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List all distributions with their member counts and target groups",
+		Args:  cobra.NoArgs,
+		Run:   distributionListRun,
+	}
+
+	distributionShowCmd = &cobra.Command{
+		Use:   "show <distribution>",
+		Short: "Show the effective config of a distribution",
+		Args:  cobra.ExactArgs(1),
+		Run:   distributionShowRun,
+	}
+
+	distributionCreateCmd = &cobra.Command{
+		Use:   "create <distribution>",
+		Short: "Create a new distribution from a template, or from an existing distribution",
+		Args:  cobra.ExactArgs(1),
+		Run:   distributionCreateRun,
+	}
+
+	distributionCopyCmd = &cobra.Command{
+		Use:   "copy <source distribution> <new distribution>",
+		Short: "Create a new distribution as a copy of an existing one (e.g. milestone to retake)",
+		Args:  cobra.ExactArgs(2),
+		Run:   distributionCopyRun,
+	}
+
+	distributionDeleteCmd = &cobra.Command{
+		Use:   "delete <distribution>",
+		Short: "Delete a distribution, after confirmation",
+		Args:  cobra.ExactArgs(1),
+		Run:   distributionDeleteRun,
+	}
+)
+
+func init() {
+	log.Debug("distribution.init()")
+	distributionCreateCmd.Flags().StringVar(&DistributionFromFlag, "from", "",
+		"existing distribution to copy the config from (default: a template)")
+	for _, cmd := range []*cobra.Command{distributionCreateCmd, distributionCopyCmd} {
+		cmd.Flags().BoolVar(&DistributionWithIndividualizationFlag, "with-individualization", false,
+			"also copy the saved individual_repositories file of the source distribution")
+	}
+	distributionCmd.AddCommand(distributionListCmd, distributionShowCmd, distributionCreateCmd,
+		distributionCopyCmd, distributionDeleteCmd)
+	rootCmd.AddCommand(distributionCmd)
+}
+
+// Same as the root command's hook, but aborts if no origin repo is given
+func originRepoPersistentPreRun(cmd *cobra.Command, args []string) {
+	persistentPreRun(cmd, args)
+	log.Debug("subcmd.originRepoPersistentPreRun()")
+	if origin.OriginRepo == nil {
+		utils.AbortWithError(utils.ErrUsage, "You need to specify an origin repo with -o / --originrepo")
+	}
+}
+
+func distributionListRun(cmd *cobra.Command, args []string) {
+	log.Debug("distribution.list()")
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "DISTRIBUTION\tREPOS\tMEMBERS\tCODE GROUP\tTEST GROUP\tINDIVIDUALIZED")
+	var firstErr error
+	for _, distributionName := range origin.OriginRepo.DistributionNames() {
+		distribution := origin.OriginRepo.GetDistribution(distributionName)
+		if err := distribution.RepositoryConfigFile.ParseContent(); err != nil {
+			// the other distributions are listed anyway
+			fmt.Fprintf(writer, "%s\t-\t-\t-\t-\terror: %v\n", distributionName, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		content := distribution.RepositoryConfigFile.Content
+		memberCount := 0
+		for _, members := range content.Repository.RepositoryMembers {
+			memberCount += len(members)
+		}
+		repoCount := len(content.Repository.RepositoryMembers)
+		if repoCount == 0 {
+			repoCount = content.Repository.RepositoryCount
+		}
+		individualized := "no"
+		if distribution.IndividualizationConfigFileName != "" {
+			individualized = filepath.Base(distribution.IndividualizationConfigFileName)
+		}
+		fmt.Fprintf(writer, "%s\t%d\t%d\t%d\t%d\t%s\n", distributionName, repoCount, memberCount,
+			content.Remote.CodeRepositoryTargetGroupId, content.Remote.TestRepositoryTargetGroupId, individualized)
+	}
+	writer.Flush()
+	if firstErr != nil {
+		utils.Exit(utils.ExitCode(firstErr))
+	}
+}
+
+func distributionShowRun(cmd *cobra.Command, args []string) {
+	log.Debug("distribution.show()")
+	distribution := getDistributionOrFail(args[0])
+	repositoryConfigFile := readDistributionConfig(args[0])
+	fmt.Printf("Distribution:          %s\n", args[0])
+	fmt.Printf("Folder:                %s\n", distribution.Dir)
+	fmt.Printf("Config file:           %s\n", repositoryConfigFile.FilePath)
+	individualizationFile := "(none - not distributed yet)"
+	if distribution.IndividualizationConfigFileName != "" {
+		individualizationFile = distribution.IndividualizationConfigFileName
+	}
+	fmt.Printf("Individualization:     %s\n", individualizationFile)
+	fmt.Printf("Patch history:         %s\n\n", distribution.PatchHistoryFile.FilePath)
+	content, err := repositoryConfigFile.MarshalContent()
+	utils.OutputAndAbortIfError(err)
+	fmt.Print(string(content))
+}
+
+func distributionCreateRun(cmd *cobra.Command, args []string) {
+	log.Debug("distribution.create()")
+	acquireRunLock("distribution create")
+	if DistributionFromFlag != "" {
+		copyDistribution(DistributionFromFlag, args[0])
+		return
+	}
+	distribution, err := origin.OriginRepo.CreateDistribution(args[0], ars.NewRepositoryConfigTemplate(""))
+	utils.OutputAndAbortIfError(err)
+	log.Info("Created distribution " + args[0] + " from a template in " + distribution.Dir +
+		" - please edit its repositoryConfig.json.")
+}
+
+func distributionCopyRun(cmd *cobra.Command, args []string) {
+	log.Debug("distribution.copy()")
+	acquireRunLock("distribution copy")
+	copyDistribution(args[0], args[1])
+}
+
+func copyDistribution(srcName, destName string) {
+	getDistributionOrFail(srcName)
+	distribution, err := origin.OriginRepo.CopyDistribution(srcName, destName, DistributionWithIndividualizationFlag)
+	utils.OutputAndAbortIfError(err)
+	log.Info("Created distribution " + destName + " as a copy of " + srcName + " in " + distribution.Dir +
+		" - please check its repositoryConfig.json.")
+}
+
+func distributionDeleteRun(cmd *cobra.Command, args []string) {
+	log.Debug("distribution.delete()")
+	acquireRunLock("distribution delete")
+	distribution := getDistributionOrFail(args[0])
+	details := []string{"folder: " + distribution.Dir}
+	if distribution.IndividualizationConfigFileName != "" {
+		details = append(details, "saved individualization: "+filepath.Base(distribution.IndividualizationConfigFileName))
+	}
+	if entries, err := distribution.PatchHistoryFile.ReadEntries(); err == nil && len(entries) > 0 {
+		details = append(details, fmt.Sprintf("patch history: %d entries", len(entries)))
+	}
+	if repositoryConfigFile := distribution.RepositoryConfigFile; repositoryConfigFile.ParseContent() == nil {
+		details = append(details, repositoryConfigDetails(repositoryConfigFile)...)
+	}
+	utils.RequireConfirmations(utils.ConfirmationType{
+		Action:  fmt.Sprintf("Delete distribution '%s' with all its files (the repos on GitLab are not touched)", args[0]),
+		Details: details,
+	})
+	utils.OutputAndAbortIfError(origin.OriginRepo.DeleteDistribution(args[0]))
+	log.Info("Deleted distribution " + args[0])
+}
+
+func getDistributionOrFail(distributionName string) *origin.Distribution {
+	distribution := origin.OriginRepo.GetDistribution(distributionName)
+	if distribution == nil {
+		utils.AbortWithError(utils.ErrNotFound, "Distribution %s not found", distributionName)
+	}
+	return distribution
+}
+
+// Reads the repositoryConfig.json of a distribution, without validating it
+func readDistributionConfig(distributionName string) *ars.RepositoryConfigFileType {
+	repositoryConfigFile := getDistributionOrFail(distributionName).RepositoryConfigFile
+	err := repositoryConfigFile.ParseContent()
+	if err != nil {
+		utils.OutputAndAbortIfError(fmt.Errorf("Error reading the config of distribution %s: %w", distributionName, err))
+	}
+	return repositoryConfigFile
+}
+
+// Describes the repos a config creates or patches, for confirmations: the number of repos and members, and
+// the target group IDs
+func repositoryConfigDetails(repositoryConfigFile *ars.RepositoryConfigFileType) []string {
+	content := &repositoryConfigFile.Content
+	repoCount := len(content.Repository.RepositoryMembers)
+	if repoCount == 0 {
+		repoCount = content.Repository.RepositoryCount
+	}
+	memberCount := 0
+	for _, members := range content.Repository.RepositoryMembers {
+		memberCount += len(members)
+	}
+	details := []string{
+		fmt.Sprintf("code repos: %d in group %d", repoCount, content.Remote.CodeRepositoryTargetGroupId),
+	}
+	if content.General.CreateTestRepository {
+		details = append(details,
+			fmt.Sprintf("test repos: %d in group %d", repoCount, content.Remote.TestRepositoryTargetGroupId))
+	}
+	details = append(details, fmt.Sprintf("members: %d", memberCount))
+	if content.Overview.GenerateOverview {
+		details = append(details, fmt.Sprintf("overview repo: %d", content.Overview.OverviewRepositoryId))
+	}
+	return details
+}
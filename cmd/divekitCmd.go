@@ -4,8 +4,10 @@ import (
 	"divekit-cli/divekit"
 	"divekit-cli/divekit/origin"
 	"divekit-cli/utils"
+	"fmt"
 	"github.com/apex/log"
 	"github.com/spf13/cobra"
+	"strings"
 )
 
 var (
@@ -13,6 +15,8 @@ var (
 	OriginRepoNameFlag string
 	LogLevelFlag       string
 	DivekitHomeFlag    string
+	InPlaceFlag        bool
+	AcceptRiskFlag     []string
 
 	rootCmd = &cobra.Command{
 		Use:   "divekit",
@@ -28,6 +32,7 @@ realistic software engineering exercises as Git repos.`,
 
 func init() {
 	log.Debug("divekit.init()")
+	rootCmd.Long += "\n\n" + exitCodesHelp()
 	rootCmd.PersistentFlags().BoolVarP(&utils.DryRunFlag, "dry-run", "0", false,
 		"just tell what you would do, but don't do it yet")
 	rootCmd.PersistentFlags().StringVarP(&LogLevelFlag, "loglevel", "l", "info",
@@ -36,13 +41,32 @@ func init() {
 		"name of the origin repo to work with")
 	rootCmd.PersistentFlags().StringVarP(&DivekitHomeFlag, "home", "m", "",
 		"home directory of all the Divekit repos")
+	rootCmd.PersistentFlags().BoolVar(&InPlaceFlag, "in-place", false,
+		"run the ARS and Repo Editor in their own repos, instead of in a temporary copy of them")
+	rootCmd.PersistentFlags().BoolVarP(&utils.AssumeYesFlag, "yes", "y", false,
+		"confirm all confirmations in advance (same as "+utils.AssumeYesEnvVar+"=1)")
+	rootCmd.PersistentFlags().BoolVar(&utils.NoInputFlag, "no-input", false,
+		"never ask for input: fail if a confirmation is needed, and use the defaults for other prompts")
+	rootCmd.PersistentFlags().StringSliceVar(&AcceptRiskFlag, "accept-risk", []string{},
+		"accept the risk of these safety rules for this run (see 'divekit config check')")
 }
 
 func persistentPreRun(cmd *cobra.Command, args []string) {
 	utils.DefineLoggingLevel(LogLevelFlag)
 	log.Debug("divekit.persistentPreRun()")
-	divekit.InitDivekitHomeDir(DivekitHomeFlag)
-	origin.InitOriginRepo(OriginRepoNameFlag)
+	utils.OutputAndAbortIfError(divekit.InitDivekitHomeDir(DivekitHomeFlag))
+	utils.OutputAndAbortIfError(divekit.InitMachineSettings())
+	utils.OutputAndAbortIfError(origin.InitOriginRepo(OriginRepoNameFlag))
+}
+
+// Lists the exit codes of the error classes, for the help text
+func exitCodesHelp() string {
+	builder := &strings.Builder{}
+	builder.WriteString("Exit codes:\n  0  success\n")
+	for _, errorClass := range utils.ErrorClasses {
+		builder.WriteString(fmt.Sprintf("  %d  %s: %s\n", errorClass.ExitCode, errorClass.Name, errorClass.Description))
+	}
+	return strings.TrimSuffix(builder.String(), "\n")
 }
 
 func Execute() error {
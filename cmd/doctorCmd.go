@@ -0,0 +1,353 @@
+package cmd
+
+import (
+	"divekit-cli/divekit"
+	"divekit-cli/divekit/ars"
+	"divekit-cli/divekit/origin"
+	"divekit-cli/divekit/patch"
+	"divekit-cli/divekit/runlock"
+	"divekit-cli/utils"
+	"encoding/json"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// Flags
+	DoctorJSONFlag bool
+
+	doctorCmd = &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the environment and the layout of all Divekit repos",
+		Long: `Check everything the other commands rely on, and report all problems at once: the Divekit home dir,
+the ARS and Repo Editor repos, npm and node, and the layout of the origin repo(s). If no origin repo
+is given with -o, all origin repos found in the home dir are checked.`,
+		Args: cobra.NoArgs,
+		// overrides the root command's hook, which would abort at the first problem
+		PersistentPreRun: doctorPersistentPreRun,
+		Run:              doctorRun,
+	}
+)
+
+// status of a single doctor check
+const (
+	doctorOK      = "ok"
+	doctorWarning = "warning"
+	doctorError   = "error"
+)
+
+type doctorCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	// the class of the error, if the status is doctorError
+	errorClass *utils.ErrorClassType
+}
+
+type doctorReport struct {
+	Checks   []doctorCheck `json:"checks"`
+	Errors   int           `json:"errors"`
+	Warnings int           `json:"warnings"`
+}
+
+func init() {
+	log.Debug("doctor.init()")
+	doctorCmd.Flags().BoolVar(&DoctorJSONFlag, "json", false, "output the result as JSON")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+func doctorPersistentPreRun(cmd *cobra.Command, args []string) {
+	if DoctorJSONFlag {
+		// keep stdout clean for the JSON output
+		LogLevelFlag = "error"
+	}
+	utils.DefineLoggingLevel(LogLevelFlag)
+	log.Debug("doctor.persistentPreRun()")
+}
+
+func doctorRun(cmd *cobra.Command, args []string) {
+	log.Debug("doctor.run()")
+	report := &doctorReport{Checks: []doctorCheck{}}
+	if report.checkDivekitHome() {
+		report.checkMachineSettings()
+		report.checkARSRepo()
+		report.checkPatchRepo()
+		report.checkOriginRepos()
+		report.checkRunLock()
+	}
+	report.checkToolVersion("node", "node")
+	report.checkToolVersion("npm", "npm")
+	report.checkToolVersion("git", "git")
+	report.print()
+	for _, check := range report.Checks {
+		if check.Status == doctorError {
+			// the exit code of the first error, as if a command had run into it
+			utils.Exit(check.errorClass.ExitCode)
+		}
+	}
+}
+
+func (report *doctorReport) add(name, status, message string) {
+	report.Checks = append(report.Checks, doctorCheck{Name: name, Status: status, Message: message})
+	if status == doctorWarning {
+		report.Warnings++
+	}
+}
+
+// Adds a failed check, with the class of the error
+func (report *doctorReport) addError(name string, err error) {
+	report.Checks = append(report.Checks, doctorCheck{Name: name, Status: doctorError, Message: err.Error(),
+		errorClass: utils.ErrorClassOf(err)})
+	report.Errors++
+}
+
+// Adds one check per error, or a single "ok" check if there are none
+func (report *doctorReport) addErrors(name string, errorsList []error) bool {
+	if len(errorsList) == 0 {
+		report.add(name, doctorOK, "")
+		return true
+	}
+	for _, err := range errorsList {
+		report.addError(name, err)
+	}
+	return false
+}
+
+func (report *doctorReport) checkDivekitHome() bool {
+	log.Debug("doctor.checkDivekitHome()")
+	homeDir, source := divekit.DetermineDivekitHomeDir(DivekitHomeFlag)
+	divekit.DivekitHomeDir = homeDir
+	err := utils.ValidateDirPath(homeDir)
+	if err != nil {
+		report.addError("Divekit home dir", fmt.Errorf("%w (home dir %s)", err, source))
+		return false
+	}
+	report.add("Divekit home dir", doctorOK, homeDir+" ("+source+")")
+	return true
+}
+
+func (report *doctorReport) checkMachineSettings() {
+	log.Debug("doctor.checkMachineSettings()")
+	settings, err := divekit.NewMachineSettingsFile()
+	switch {
+	case err != nil:
+		report.addError("Machine CLI settings", err)
+	case !settings.Exists:
+		report.add("Machine CLI settings", doctorOK, "none - the tools are run with their defaults")
+	default:
+		report.add("Machine CLI settings", doctorOK, settings.FilePath)
+		report.checkToolConfigs("Machine CLI settings", settings.ToolConfigs())
+	}
+}
+
+func (report *doctorReport) checkARSRepo() {
+	log.Debug("doctor.checkARSRepo()")
+	arsRepo := ars.NewARSRepoLayout()
+	if !report.addErrors("ARS repo layout", arsRepo.Validate()) {
+		return
+	}
+	report.checkNodeModules("ARS", arsRepo.RepoDir)
+}
+
+func (report *doctorReport) checkPatchRepo() {
+	log.Debug("doctor.checkPatchRepo()")
+	patchRepo := patch.NewPatchRepoLayout()
+	if !report.addErrors("Repo Editor repo layout", patchRepo.Validate()) {
+		return
+	}
+	report.checkNodeModules("Repo Editor", patchRepo.RepoDir)
+}
+
+func (report *doctorReport) checkRunLock() {
+	log.Debug("doctor.checkRunLock()")
+	runLock, err := runlock.Load()
+	switch {
+	case err != nil:
+		report.addError("Run lock", err)
+	case runLock == nil:
+		report.add("Run lock", doctorOK, "not locked")
+	default:
+		if stale, reason := runLock.IsStale(); stale {
+			report.add("Run lock", doctorWarning, "stale lock of "+runLock.Describe()+" ("+reason+
+				") - it is removed by the next run, or by 'divekit unlock'")
+		} else {
+			report.add("Run lock", doctorWarning, "locked by "+runLock.Describe())
+		}
+	}
+}
+
+func (report *doctorReport) checkNodeModules(toolName, repoDir string) {
+	nodeModulesDir := filepath.Join(repoDir, "node_modules")
+	if utils.ValidateDirPath(nodeModulesDir) != nil {
+		report.addError(toolName+" node_modules", utils.NewError(utils.ErrNotFound,
+			"node_modules missing in %s - please run 'npm install' there", repoDir))
+		return
+	}
+	report.add(toolName+" node_modules", doctorOK, "")
+}
+
+func (report *doctorReport) checkToolVersion(name, executable string) {
+	log.Debug("doctor.checkToolVersion() - executable: " + executable)
+	checkName := name + " on PATH"
+	path, err := exec.LookPath(executable)
+	if err != nil {
+		if executable == "git" {
+			// git is only used for the patch history
+			report.add(checkName, doctorWarning, executable+" not found on PATH")
+		} else {
+			report.addError(checkName, utils.NewError(utils.ErrNotFound, "%s not found on PATH", executable))
+		}
+		return
+	}
+	output, err := exec.Command(path, "--version").Output()
+	if err != nil {
+		report.add(checkName, doctorWarning, fmt.Sprintf("%s found, but '%s --version' failed: %v", path, executable, err))
+		return
+	}
+	report.add(checkName, doctorOK, strings.TrimSpace(string(output))+" ("+path+")")
+}
+
+func (report *doctorReport) checkOriginRepos() {
+	log.Debug("doctor.checkOriginRepos()")
+	if OriginRepoNameFlag != "" {
+		report.checkOriginRepo(OriginRepoNameFlag)
+		return
+	}
+	subfolders, err := utils.ListSubfolderNames(divekit.DivekitHomeDir)
+	if err != nil {
+		report.addError("Origin repos", err)
+		return
+	}
+	found := false
+	for _, subfolder := range subfolders {
+		if utils.ValidateDirPath(filepath.Join(divekit.DivekitHomeDir, subfolder, ".divekit_norepo")) == nil {
+			found = true
+			report.checkOriginRepo(subfolder)
+		}
+	}
+	if !found {
+		report.add("Origin repos", doctorWarning, "no origin repo (with a .divekit_norepo folder) found in "+
+			divekit.DivekitHomeDir)
+	}
+}
+
+func (report *doctorReport) checkOriginRepo(originRepoName string) {
+	log.Debug("doctor.checkOriginRepo() - originRepoName: " + originRepoName)
+	checkName := "Origin repo " + originRepoName
+	repoDir := filepath.Join(divekit.DivekitHomeDir, originRepoName)
+	distributionsDir := filepath.Join(repoDir, origin.DistributionsDirName)
+	if !report.addErrors(checkName+" layout", utils.ValidateAllDirPaths(repoDir, distributionsDir,
+		filepath.Join(repoDir, origin.ARSConfigDirName))) {
+		return
+	}
+
+	cliSettingsFile := filepath.Join(repoDir, origin.CLISettingsFileName)
+	if utils.ValidateFilePath(cliSettingsFile) == nil {
+		settings := &origin.CLISettingsFileType{FilePath: cliSettingsFile}
+		if err := settings.ReadContent(); err != nil {
+			report.addError(checkName+" CLI settings", err)
+		} else {
+			report.add(checkName+" CLI settings", doctorOK, "")
+			report.checkToolConfigs(checkName, settings.ToolConfigs())
+		}
+	}
+
+	distributionNames, err := utils.ListSubfolderNames(distributionsDir)
+	if err != nil {
+		report.addError(checkName+" distributions", err)
+		return
+	}
+	if len(distributionNames) == 0 {
+		report.add(checkName+" distributions", doctorWarning, "no distributions in "+distributionsDir)
+	}
+	for _, distributionName := range distributionNames {
+		report.checkDistribution(checkName+", distribution "+distributionName,
+			filepath.Join(distributionsDir, distributionName))
+	}
+}
+
+// Checks that the executables configured for the tools in the CLI settings can be found
+func (report *doctorReport) checkToolConfigs(checkName string, toolConfigs map[string]utils.ToolConfigType) {
+	log.Debug("doctor.checkToolConfigs()")
+	for _, toolName := range []string{utils.ToolARS, utils.ToolRepoEditor} {
+		executable := toolConfigs[toolName].Executable
+		if executable == "" {
+			continue
+		}
+		executable = os.ExpandEnv(executable)
+		if strings.Contains(executable, "/") && !filepath.IsAbs(executable) {
+			// relative to the working dir of the tool, which only exists during a run
+			continue
+		}
+		path, err := exec.LookPath(executable)
+		if err != nil {
+			report.addError(checkName+" "+toolName+" executable", utils.NewError(utils.ErrNotFound, "%s not found",
+				executable))
+			continue
+		}
+		report.add(checkName+" "+toolName+" executable", doctorOK, path)
+	}
+}
+
+func (report *doctorReport) checkDistribution(checkName, distributionDir string) {
+	log.Debug("doctor.checkDistribution() - distributionDir: " + distributionDir)
+	repositoryConfigFile := &ars.RepositoryConfigFileType{
+		FilePath: filepath.Join(distributionDir, origin.RepositoryConfigFileName),
+	}
+	var issues []ars.ValidationIssue
+	err := utils.ValidateFilePath(repositoryConfigFile.FilePath)
+	if err == nil {
+		issues, err = repositoryConfigFile.Validate(ars.ValidateForRemoteMode)
+	}
+	switch {
+	case err != nil:
+		report.addError(checkName+" repositoryConfig.json", err)
+	case len(issues) == 0:
+		report.add(checkName+" repositoryConfig.json", doctorOK, "")
+	default:
+		message := fmt.Sprintf("%d issue(s), e.g. line %d: %s %s - see 'divekit config validate'",
+			len(issues), issues[0].Line, issues[0].Path, issues[0].Message)
+		if ars.HasValidationErrors(issues) {
+			report.addError(checkName+" repositoryConfig.json", utils.NewError(utils.ErrInvalidConfig, "%s", message))
+		} else {
+			report.add(checkName+" repositoryConfig.json", doctorWarning, message)
+		}
+	}
+
+	individualizationFiles, err :=
+		utils.FindFilesWithPrefix(distributionDir, origin.IndividualRepositoriesFilePrefix)
+	switch {
+	case err != nil:
+		report.addError(checkName+" individualization file", err)
+	case len(individualizationFiles) == 0:
+		report.add(checkName+" individualization file", doctorWarning,
+			"no individual_repositories file - the distribution can't be patched before it has been distributed")
+	case len(individualizationFiles) > 1:
+		report.addError(checkName+" individualization file", utils.NewError(utils.ErrAmbiguous,
+			"multiple individual_repositories files: %s", strings.Join(individualizationFiles, ", ")))
+	default:
+		report.add(checkName+" individualization file", doctorOK, filepath.Base(individualizationFiles[0]))
+	}
+}
+
+func (report *doctorReport) print() {
+	if DoctorJSONFlag {
+		output, err := json.MarshalIndent(report, "", "  ")
+		utils.OutputAndAbortIfError(err)
+		fmt.Println(string(output))
+		return
+	}
+	for _, check := range report.Checks {
+		line := fmt.Sprintf("[%-7s] %s", strings.ToUpper(check.Status), check.Name)
+		if check.Message != "" {
+			line += ": " + check.Message
+		}
+		fmt.Println(line)
+	}
+	fmt.Printf("\n%d error(s), %d warning(s)\n", report.Errors, report.Warnings)
+}
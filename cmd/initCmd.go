@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"divekit-cli/divekit"
+	"divekit-cli/divekit/ars"
+	"divekit-cli/divekit/origin"
+	"divekit-cli/utils"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// Flags
+	InitOriginIdFlag          int
+	InitNamePatternFlag       string
+	InitCodeGroupFlag         int
+	InitTestGroupFlag         int
+	InitOverviewRepoFlag      int
+	InitMembersFlag           string
+	InitStaffCodeGroupFlag    int
+	InitStaffTestGroupFlag    int
+	InitStaffOverviewRepoFlag int
+	InitStaffMembersFlag      string
+
+	initCmd = &cobra.Command{
+		Use:   "init",
+		Short: "Scaffold the Divekit folders in a new origin repo",
+		Long: `Create the .divekit_norepo/distributions and ars-config_norepo folders in the origin repo given by -o,
+with a "milestone" distribution for the students and a "test" distribution for the staff. Values that
+are not given as flags are asked for interactively.`,
+		Args: cobra.NoArgs,
+		// overrides the root command's hook, which expects the origin repo to be complete already
+		PersistentPreRun: initPersistentPreRun,
+		Run:              initRun,
+	}
+)
+
+func init() {
+	log.Debug("init.init()")
+	initCmd.Flags().IntVar(&InitOriginIdFlag, "origin-id", 0, "GitLab project ID of the origin repo")
+	initCmd.Flags().StringVar(&InitNamePatternFlag, "name-pattern", "",
+		"name pattern of the repos, e.g. st2-m1-{{uuid}}")
+	initCmd.Flags().IntVar(&InitCodeGroupFlag, "code-group", 0,
+		"GitLab group ID for the students' code repos")
+	initCmd.Flags().IntVar(&InitTestGroupFlag, "test-group", 0,
+		"GitLab group ID for the students' test repos")
+	initCmd.Flags().IntVar(&InitOverviewRepoFlag, "overview-repo", 0,
+		"GitLab project ID of the overview repo for the students' repos (0 = no overview)")
+	initCmd.Flags().StringVar(&InitMembersFlag, "members", "",
+		"students' campus IDs, separated by commas, team members joined by +")
+	initCmd.Flags().IntVar(&InitStaffCodeGroupFlag, "staff-code-group", 0,
+		"GitLab group ID for the staff's code repos")
+	initCmd.Flags().IntVar(&InitStaffTestGroupFlag, "staff-test-group", 0,
+		"GitLab group ID for the staff's test repos")
+	initCmd.Flags().IntVar(&InitStaffOverviewRepoFlag, "staff-overview-repo", 0,
+		"GitLab project ID of the overview repo for the staff's repos (0 = no overview)")
+	initCmd.Flags().StringVar(&InitStaffMembersFlag, "staff-members", "",
+		"staff's campus IDs, separated by commas")
+	rootCmd.AddCommand(initCmd)
+}
+
+func initPersistentPreRun(cmd *cobra.Command, args []string) {
+	utils.DefineLoggingLevel(LogLevelFlag)
+	log.Debug("init.persistentPreRun()")
+	utils.OutputAndAbortIfError(divekit.InitDivekitHomeDir(DivekitHomeFlag))
+	if OriginRepoNameFlag == "" {
+		utils.AbortWithError(utils.ErrUsage, "You need to specify the origin repo to initialize with -o / --originrepo")
+	}
+}
+
+func initRun(cmd *cobra.Command, args []string) {
+	log.Debug("init.run()")
+	repoDir := filepath.Join(divekit.DivekitHomeDir, OriginRepoNameFlag)
+	utils.OutputAndAbortIfError(utils.ValidateDirPath(repoDir))
+	acquireRunLock("init")
+	utils.OutputAndAbortIfError(origin.ScaffoldOriginRepo(repoDir))
+
+	originId := promptIntUnlessSet(cmd, "origin-id", InitOriginIdFlag, "GitLab project ID of the origin repo")
+	namePattern := promptUnlessSet(cmd, "name-pattern", InitNamePatternFlag,
+		"Name pattern of the repos", strings.TrimSuffix(OriginRepoNameFlag, "-origin")+"-{{uuid}}")
+
+	fmt.Println("\nDistribution 'milestone' (students):")
+	milestoneConfig := newDistributionConfig(originId, namePattern,
+		promptIntUnlessSet(cmd, "code-group", InitCodeGroupFlag, "GitLab group ID for the code repos"),
+		promptIntUnlessSet(cmd, "test-group", InitTestGroupFlag, "GitLab group ID for the test repos"),
+		promptIntUnlessSet(cmd, "overview-repo", InitOverviewRepoFlag, "GitLab project ID of the overview repo"),
+		promptUnlessSet(cmd, "members", InitMembersFlag,
+			"Campus IDs (separated by commas, team members joined by +)", ""))
+	scaffoldDistribution(repoDir, "milestone", milestoneConfig)
+
+	fmt.Println("\nDistribution 'test' (staff):")
+	testConfig := newDistributionConfig(originId, "test-"+namePattern,
+		promptIntUnlessSet(cmd, "staff-code-group", InitStaffCodeGroupFlag, "GitLab group ID for the code repos"),
+		promptIntUnlessSet(cmd, "staff-test-group", InitStaffTestGroupFlag, "GitLab group ID for the test repos"),
+		promptIntUnlessSet(cmd, "staff-overview-repo", InitStaffOverviewRepoFlag,
+			"GitLab project ID of the overview repo"),
+		promptUnlessSet(cmd, "staff-members", InitStaffMembersFlag, "Campus IDs (separated by commas)", ""))
+	scaffoldDistribution(repoDir, "test", testConfig)
+
+	log.Info("Initialized origin repo " + repoDir + " - please check the generated repositoryConfig.json files.")
+}
+
+func newDistributionConfig(originId int, namePattern string, codeGroupId, testGroupId, overviewRepoId int,
+	memberList string) *ars.RepositoryConfigFileType {
+	repositoryConfigFile := ars.NewRepositoryConfigTemplate("")
+	content := &repositoryConfigFile.Content
+	content.Repository.RepositoryName = namePattern
+	content.Repository.RepositoryMembers = ars.ParseRepositoryMembers(memberList)
+	content.Repository.RepositoryCount = len(content.Repository.RepositoryMembers)
+	content.Remote.OriginRepositoryId = originId
+	content.Remote.CodeRepositoryTargetGroupId = codeGroupId
+	content.Remote.TestRepositoryTargetGroupId = testGroupId
+	content.General.CreateTestRepository = testGroupId != 0
+	content.Overview.OverviewRepositoryId = overviewRepoId
+	content.Overview.GenerateOverview = overviewRepoId != 0
+	return repositoryConfigFile
+}
+
+func scaffoldDistribution(repoDir, distributionName string, repositoryConfigFile *ars.RepositoryConfigFileType) {
+	err := origin.ScaffoldDistribution(repoDir, distributionName, repositoryConfigFile)
+	if err != nil {
+		log.Warnf("Skipping distribution %s: %v", distributionName, err)
+		return
+	}
+	log.Info(fmt.Sprintf("Created distribution %s with %d repo(s) in %s", distributionName,
+		len(repositoryConfigFile.Content.Repository.RepositoryMembers), repositoryConfigFile.FilePath))
+}
+
+func promptUnlessSet(cmd *cobra.Command, flagName, flagValue, prompt, defaultValue string) string {
+	if cmd.Flags().Changed(flagName) {
+		return flagValue
+	}
+	return utils.Prompt(prompt, defaultValue)
+}
+
+func promptIntUnlessSet(cmd *cobra.Command, flagName string, flagValue int, prompt string) int {
+	if cmd.Flags().Changed(flagName) {
+		return flagValue
+	}
+	return utils.PromptInt(prompt, 0)
+}
@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"divekit-cli/divekit/ars"
+	"divekit-cli/divekit/roster"
+	"divekit-cli/utils"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	// Flags
+	MembersIdColumnFlag   string
+	MembersTeamColumnFlag string
+	MembersDelimiterFlag  string
+	MembersNoHeaderFlag   bool
+	MembersIdPatternFlag  string
+	MembersAppendFlag     bool
+	MembersTeamSizeFlag   int
+	MembersStrategyFlag   string
+	MembersSeedFlag       int64
+	MembersPreferenceFlag string
+	MembersRemainderFlag  string
+
+	membersCmd = &cobra.Command{
+		Use:              "members",
+		Short:            "Manage the members of a distribution",
+		Long:             `Import, export, and form teams of the repositoryMembers of a distribution's repositoryConfig.json`,
+		PersistentPreRun: originRepoPersistentPreRun,
+	}
+
+	membersImportCmd = &cobra.Command{
+		Use:   "import <distribution> <roster.csv>",
+		Short: "Import the members of a distribution from a CSV roster",
+		Long: `Read a CSV roster (e.g. an export of a campus list) and set the repositoryMembers of the distribution:
+one repo per student, or one repo per team if the roster has a team column. The campus ID and team
+columns are detected by their header names, or can be given by name or number.`,
+		Args: cobra.ExactArgs(2),
+		Run:  membersImportRun,
+	}
+
+	membersExportCmd = &cobra.Command{
+		Use:   "export <distribution> [roster.csv]",
+		Short: "Export the members of a distribution as CSV roster (to stdout if no file is given)",
+		Args:  cobra.RangeArgs(1, 2),
+		Run:   membersExportRun,
+	}
+
+	membersTeamsCmd = &cobra.Command{
+		Use:   "teams <distribution> [roster.csv]",
+		Short: "Form teams of the students in a roster, and set them as members of a distribution",
+		Long: `Form teams of the given size from the students in a CSV roster (or, if no roster is given, from the
+current members of the distribution), and set them as repositoryMembers of the distribution. The teams are
+formed randomly, alphabetically, or from a preference file, in which each line lists the campus IDs of
+students who want to be in the same team. The same roster, flags and seed always give the same teams.`,
+		Args:   cobra.RangeArgs(1, 2),
+		PreRun: membersTeamsPreRun,
+		Run:    membersTeamsRun,
+	}
+)
+
+func init() {
+	log.Debug("members.init()")
+	for _, cmd := range []*cobra.Command{membersImportCmd, membersTeamsCmd} {
+		cmd.Flags().StringVar(&MembersIdColumnFlag, "id-column", "",
+			"name or number of the campus ID column (default: detected from the header)")
+		cmd.Flags().StringVar(&MembersDelimiterFlag, "delimiter", "",
+			"delimiter of the CSV file (default: comma or semicolon, detected)")
+		cmd.Flags().BoolVar(&MembersNoHeaderFlag, "no-header", false,
+			"the CSV file has no header line")
+		cmd.Flags().StringVar(&MembersIdPatternFlag, "id-pattern", roster.DefaultCampusIdPattern,
+			"regular expression that valid campus IDs match")
+	}
+	membersImportCmd.Flags().StringVar(&MembersTeamColumnFlag, "team-column", "",
+		"name or number of the team column, \"-\" for none (default: detected from the header)")
+	membersImportCmd.Flags().BoolVar(&MembersAppendFlag, "append", false,
+		"add the imported members to the existing ones, instead of replacing them")
+	membersTeamsCmd.Flags().IntVar(&MembersTeamSizeFlag, "size", 2, "number of students per team")
+	membersTeamsCmd.Flags().StringVar(&MembersStrategyFlag, "strategy", roster.StrategyRandom,
+		"how the teams are formed: "+strings.Join(roster.Strategies, ", "))
+	membersTeamsCmd.Flags().Int64Var(&MembersSeedFlag, "seed", 0,
+		"seed for the random order of the students (default: a new seed, which is logged)")
+	membersTeamsCmd.Flags().StringVar(&MembersPreferenceFlag, "preferences", "",
+		"preference file for the preferences strategy")
+	membersTeamsCmd.Flags().StringVar(&MembersRemainderFlag, "remainder", roster.RemainderBalance,
+		"what happens to students that don't fill a complete team: "+strings.Join(roster.RemainderPolicies, ", "))
+	membersCmd.AddCommand(membersImportCmd, membersExportCmd, membersTeamsCmd)
+	rootCmd.AddCommand(membersCmd)
+}
+
+func membersImportRun(cmd *cobra.Command, args []string) {
+	log.Debug("members.import()")
+	acquireRunLock("members import")
+	repositoryConfigFile := readDistributionConfig(args[0])
+	result := importRoster(args[1], MembersTeamColumnFlag)
+
+	repository := &repositoryConfigFile.Content.Repository
+	if MembersAppendFlag {
+		result.RepositoryMembers = appendNewMembers(repository.RepositoryMembers, result.RepositoryMembers)
+	}
+	if utils.DryRunFlag {
+		for index, members := range result.RepositoryMembers {
+			fmt.Printf("Repo %3d: %s\n", index+1, strings.Join(members, ", "))
+		}
+		log.Info(fmt.Sprintf("Dry run - the %d repo(s) are not written to distribution %s (%d malformed, "+
+			"%d duplicate campus IDs skipped)", len(result.RepositoryMembers), args[0], len(result.Malformed),
+			len(result.Duplicates)))
+		return
+	}
+
+	repository.RepositoryMembers = result.RepositoryMembers
+	repository.RepositoryCount = len(repository.RepositoryMembers)
+	writeRepositoryConfigIfValid(repositoryConfigFile, ars.ValidateAsConfigured)
+	log.Info(fmt.Sprintf("Distribution %[2]s now has %[1]d repo(s) (%[3]d malformed, %[4]d duplicate campus IDs skipped)",
+		len(repository.RepositoryMembers), args[0], len(result.Malformed), len(result.Duplicates)))
+}
+
+// Imports the roster with the flags given, reports duplicates and malformed campus IDs, and aborts if
+// there are no valid campus IDs at all
+func importRoster(filePath string, teamColumn string) *roster.ImportResult {
+	options := roster.ImportOptions{
+		CampusIdColumn:  MembersIdColumnFlag,
+		TeamColumn:      teamColumn,
+		NoHeader:        MembersNoHeaderFlag,
+		CampusIdPattern: MembersIdPatternFlag,
+	}
+	if MembersDelimiterFlag != "" {
+		delimiter := []rune(strings.ReplaceAll(MembersDelimiterFlag, `\t`, "\t"))
+		if len(delimiter) != 1 {
+			utils.AbortWithError(utils.ErrUsage, "The delimiter must be a single character, not '%s'",
+				MembersDelimiterFlag)
+		}
+		options.Delimiter = delimiter[0]
+	}
+	result, err := roster.ImportRoster(filePath, options)
+	utils.OutputAndAbortIfError(err)
+
+	for _, malformed := range result.Malformed {
+		log.Warn("Skipping malformed campus ID in " + malformed)
+	}
+	for _, duplicate := range result.Duplicates {
+		log.Warn("Skipping duplicate campus ID " + duplicate)
+	}
+	if len(result.RepositoryMembers) == 0 {
+		utils.AbortWithError(utils.ErrInvalidConfig, "No valid campus IDs found in %s", filePath)
+	}
+	return result
+}
+
+// Appends the imported repos to the existing ones, skipping members that are already there
+func appendNewMembers(existingMembers, importedMembers [][]string) [][]string {
+	existing := make(map[string]bool)
+	for _, members := range existingMembers {
+		for _, member := range members {
+			existing[strings.ToLower(member)] = true
+		}
+	}
+	for _, members := range importedMembers {
+		newMembers := []string{}
+		for _, member := range members {
+			if existing[member] {
+				log.Warn("Skipping campus ID " + member + ", which is already a member")
+				continue
+			}
+			newMembers = append(newMembers, member)
+		}
+		if len(newMembers) > 0 {
+			existingMembers = append(existingMembers, newMembers)
+		}
+	}
+	return existingMembers
+}
+
+func membersExportRun(cmd *cobra.Command, args []string) {
+	log.Debug("members.export()")
+	repositoryConfigFile := readDistributionConfig(args[0])
+	writer := os.Stdout
+	if len(args) == 2 {
+		file, err := os.Create(args[1])
+		utils.OutputAndAbortIfError(err)
+		defer file.Close()
+		writer = file
+	}
+	err := roster.ExportRoster(writer, repositoryConfigFile.Content.Repository.RepositoryMembers)
+	utils.OutputAndAbortIfError(err)
+	if len(args) == 2 {
+		log.Info("Exported the members of distribution " + args[0] + " to " + args[1])
+	}
+}
+
+func membersTeamsPreRun(cmd *cobra.Command, args []string) {
+	log.Debug("members.teamsPreRun()")
+	if MembersStrategyFlag == roster.StrategyPreferences && MembersPreferenceFlag == "" {
+		utils.AbortWithError(utils.ErrUsage, "The preferences strategy needs a preference file, given with --preferences")
+	}
+	if MembersPreferenceFlag != "" && MembersStrategyFlag != roster.StrategyPreferences {
+		utils.AbortWithError(utils.ErrUsage, "--preferences can only be used with --strategy %s",
+			roster.StrategyPreferences)
+	}
+	if !cmd.Flags().Changed("seed") {
+		MembersSeedFlag = time.Now().UnixNano()
+	}
+}
+
+func membersTeamsRun(cmd *cobra.Command, args []string) {
+	log.Debug("members.teams()")
+	acquireRunLock("members teams")
+	repositoryConfigFile := readDistributionConfig(args[0])
+	repository := &repositoryConfigFile.Content.Repository
+	campusIds := []string{}
+	var memberLists [][]string
+	if len(args) == 2 {
+		memberLists = importRoster(args[1], "-").RepositoryMembers
+	} else {
+		memberLists = repository.RepositoryMembers
+	}
+	for _, members := range memberLists {
+		campusIds = append(campusIds, members...)
+	}
+
+	teams, err := roster.FormTeams(campusIds, roster.TeamOptions{
+		Size:            MembersTeamSizeFlag,
+		Strategy:        MembersStrategyFlag,
+		Seed:            MembersSeedFlag,
+		PreferencesFile: MembersPreferenceFlag,
+		Remainder:       MembersRemainderFlag,
+	})
+	utils.OutputAndAbortIfError(err)
+	for index, team := range teams {
+		fmt.Printf("Team %3d: %s\n", index+1, strings.Join(team, ", "))
+	}
+	if MembersStrategyFlag != roster.StrategyAlphabetical {
+		log.Info(fmt.Sprintf("Seed: %d (use --seed %d to form the same teams again)", MembersSeedFlag, MembersSeedFlag))
+	}
+	if utils.DryRunFlag {
+		log.Info("Dry run - the teams are not written to distribution " + args[0])
+		return
+	}
+
+	repository.RepositoryMembers = teams
+	repository.RepositoryCount = len(teams)
+	writeRepositoryConfigIfValid(repositoryConfigFile, ars.ValidateAsConfigured)
+	log.Info(fmt.Sprintf("Distribution %s now has %d team(s) of %d student(s)", args[0], len(teams), len(campusIds)))
+}
@@ -1,25 +1,38 @@
 package cmd
 
 import (
+	"divekit-cli/divekit"
 	"divekit-cli/divekit/ars"
 	"divekit-cli/divekit/origin"
 	"divekit-cli/divekit/patch"
+	"divekit-cli/divekit/runreport"
 	"divekit-cli/utils"
+	"errors"
 	"fmt"
 	"github.com/apex/log"
 	"github.com/spf13/cobra"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 var (
 	// Flags
 	DistributionNameFlag string
+	PreviewFlag          bool
+	MultipleMatchesFlag  string
+	CommitMsgFlag        string
+	CommitMsgFileFlag    string
+	PatchTargetFlag      string
+	OnlyMembersFlag      []string
+	ExcludeMembersFlag   []string
+	MembersFileFlag      string
 	// command state vars
-	PatchFiles []string
-	ARSRepo    *ars.ARSRepoType
-	PatchRepo  *patch.PatchRepoType
+	PatchFiles   []string
+	MemberFilter *ars.MemberFilterType
+	ARSRepo      *ars.ARSRepoType
+	PatchRepo    *patch.PatchRepoType
 
 	patchCmd = &cobra.Command{
 		Use:    "patch",
@@ -35,6 +48,30 @@ func init() {
 	log.Debug("patch.init()")
 	patchCmd.Flags().StringVarP(&DistributionNameFlag, "distribution", "d", "milestone",
 		"name of the repo-distribution to patch")
+	patchCmd.Flags().StringVar(&MultipleMatchesFlag, "multiple", "ask",
+		"what to do if a file name matches several files in the origin repo (ask, all, fail)")
+	patchCmd.Flags().StringVarP(&CommitMsgFlag, "message", "M", "",
+		"commit message for the patch, may be a template referencing e.g. {{.Distribution}} or {{join .Files \", \"}}")
+	patchCmd.Flags().StringVar(&CommitMsgFileFlag, "message-file", "",
+		"file containing the commit message (template) for the patch")
+	patchCmd.MarkFlagsMutuallyExclusive("message", "message-file")
+	patchCmd.Flags().StringVarP(&PatchTargetFlag, "target", "t", patch.PatchTargetBoth,
+		"which repos to patch (code, test, or both)")
+	patchCmd.Flags().StringSliceVar(&OnlyMembersFlag, "only", nil,
+		"only patch the repos of these members (comma-separated campus IDs)")
+	patchCmd.Flags().StringSliceVar(&ExcludeMembersFlag, "exclude", nil,
+		"don't patch the repos of these members (comma-separated campus IDs)")
+	patchCmd.Flags().StringVar(&MembersFileFlag, "members-file", "",
+		"only patch the repos of the members listed in this file (campus IDs, one per line)")
+	patchCmd.Flags().BoolVarP(&PreviewFlag, "preview", "p", false,
+		"generate the patch files locally and show their diffs to the origin repo, without patching any repo")
+
+	patchCmd.Flags().StringVar(&ReportFlag, "report", "",
+		"write a report of the run as JSON to this file (also if the run fails)")
+	patchCmd.Flags().StringVar(&ReportMarkdownFlag, "report-markdown", "",
+		"write a report of the run as Markdown to this file (also if the run fails)")
+
+	addTimeoutFlag(patchCmd)
 
 	patchCmd.MarkPersistentFlagRequired("originrepo")
 	rootCmd.AddCommand(patchCmd)
@@ -46,103 +83,262 @@ func validateArgs(cmd *cobra.Command, args []string) error {
 	if len(args) == 0 {
 		err = fmt.Errorf("You need to specify at least one filename to subcmd.")
 	}
+	if err == nil && MultipleMatchesFlag != "ask" && MultipleMatchesFlag != "all" && MultipleMatchesFlag != "fail" {
+		err = fmt.Errorf("Invalid value for --multiple: %s (must be ask, all, or fail)", MultipleMatchesFlag)
+	}
+	if err == nil && PatchTargetFlag != patch.PatchTargetCode && PatchTargetFlag != patch.PatchTargetTest &&
+		PatchTargetFlag != patch.PatchTargetBoth {
+		err = fmt.Errorf("Invalid value for --target: %s (must be code, test, or both)", PatchTargetFlag)
+	}
 	return err
 }
 
 // Checks preconditions before running the command
 func preRun(cmd *cobra.Command, args []string) {
-	ARSRepo = ars.NewARSRepo()
-	PatchRepo = patch.NewPatchRepo()
+	startPatchRunReport()
+	utils.OutputAndAbortIfError(utils.ParsePhaseTimeouts())
+	startPhase("check")
+	var err error
+	ARSRepo, err = ars.NewARSRepo()
+	utils.OutputAndAbortIfError(err)
+	PatchRepo, err = patch.NewPatchRepo()
+	utils.OutputAndAbortIfError(err)
 
-	distribution := origin.OriginRepo.GetDistribution(DistributionNameFlag)
-	if distribution == nil {
-		log.WithFields(log.Fields{
-			"DistributionNameFlag": DistributionNameFlag,
-		}).Fatal("Distribution not found")
+	distribution := getDistributionOrFail(DistributionNameFlag)
+	if distribution.IndividualizationConfigFileName == "" {
+		utils.AbortWithError(utils.ErrNotFound, "Distribution %s has no individual_repositories file - "+
+			"has it been distributed yet?", DistributionNameFlag)
 	}
+	utils.OutputAndAbortIfError(patch.ValidatePatchTarget(PatchTargetFlag, readDistributionConfig(DistributionNameFlag)))
+	defineMemberFilter()
+}
+
+func defineMemberFilter() {
+	log.Debug("subcmd.defineMemberFilter()")
+	onlyMembers := OnlyMembersFlag
+	if MembersFileFlag != "" {
+		membersFromFile, err := ars.ReadCampusIdsFromFile(MembersFileFlag)
+		utils.OutputAndAbortIfError(err)
+		if len(membersFromFile) == 0 {
+			utils.AbortWithError(utils.ErrUsage, "Members file %s doesn't contain any campus IDs", MembersFileFlag)
+		}
+		onlyMembers = append(onlyMembers, membersFromFile...)
+	}
+	MemberFilter = ars.NewMemberFilter(onlyMembers, ExcludeMembersFlag)
 }
 
 func run(cmd *cobra.Command, args []string) {
 	log.Debug("subcmd.run()")
+	startPhase("find files")
 	definePatchFiles(args)
+	PatchRunReport.Content.PatchFiles = PatchFiles
 	log.Info(fmt.Sprintf("Found files to patch:\n%s", strings.Join(PatchFiles, "\n")))
+	commitMsg := defineCommitMsg()
+	PatchRunReport.Content.CommitMsg = commitMsg
+	startPhase("prepare")
+	prepareToolRepos("patch")
+	PatchRunReport.Content.ToolLogFile = utils.ToolLogFilePath()
 
-	setRepositoryConfigWithinARSRepo()
-	copySavedIndividualizationFileToARS()
-	utils.RunNPMStartAlways(ARSRepo.RepoDir,
+	repositoryConfigWithinARSRepo := setRepositoryConfigWithinARSRepo()
+	enforcePolicies(newPolicyRunContext("patch", DistributionNameFlag, repositoryConfigWithinARSRepo))
+	copySavedIndividualizationFileToARS(origin.OriginRepo.GetDistribution(DistributionNameFlag))
+	startPhase("generate")
+	err := utils.RunToolAlways(utils.ToolARS, ARSRepo.RepoDir,
 		"Starting local generation of the individualized repositories containing patch files")
+	if err != nil {
+		recordPatchHistory(failureOutcome(err), commitMsg, err)
+		utils.OutputAndAbortIfError(fmt.Errorf("Error generating the patch files: %w", err))
+	}
+	recordGeneratedRepos()
 
+	if PreviewFlag {
+		startPhase("preview")
+		previewGeneratedFiles()
+		recordPatchHistory(origin.PatchOutcomePreview, commitMsg, nil)
+		PatchRunReport.Finish(origin.PatchOutcomePreview, 0, nil)
+		return
+	}
+
+	startPhase("patch")
 	copyLocallyGeneratedFilesToPatchTool()
 	distribution := origin.OriginRepo.GetDistribution(DistributionNameFlag)
-	PatchRepo.UpdatePatchConfigFile(distribution.RepositoryConfigFile)
-	utils.RunNPMStart(PatchRepo.RepoDir, "Actually patching the files to each repository")
+	err = PatchRepo.UpdatePatchConfigFile(distribution.RepositoryConfigFile, PatchTargetFlag, commitMsg)
+	if err != nil {
+		// the Repo Editor must not run with the editorConfig.json of an earlier run
+		err = fmt.Errorf("Error writing editorConfig.json for the Repo Editor: %w",
+			utils.ClassifyError(utils.ErrInvalidConfig, err))
+		recordPatchHistory(origin.PatchOutcomeFailure, commitMsg, err)
+		utils.OutputAndAbortIfError(err)
+	}
+	repoEditorOutput := &strings.Builder{}
+	err = utils.RunToolWithOutputCopy(utils.ToolRepoEditor, PatchRepo.RepoDir,
+		"Actually patching the files to each repository", repoEditorOutput)
+	if !utils.DryRunFlag {
+		PatchRunReport.EvaluateRepoEditorOutput(repoEditorOutput.String())
+		if failedRepos := PatchRunReport.ReposWithOutcome(runreport.RepoFailed); len(failedRepos) > 0 {
+			log.Warn(fmt.Sprintf("The Repo Editor reported errors for %d repo(s): %s", len(failedRepos),
+				strings.Join(failedRepos, ", ")))
+		}
+	}
+	if err != nil {
+		recordPatchHistory(failureOutcome(err), commitMsg, err)
+		utils.OutputAndAbortIfError(fmt.Errorf("Error patching the repositories: %w", err))
+	}
+	if utils.DryRunFlag {
+		recordPatchHistory(origin.PatchOutcomeDryRun, commitMsg, nil)
+		PatchRunReport.Finish(origin.PatchOutcomeDryRun, 0, nil)
+	} else {
+		recordPatchHistory(origin.PatchOutcomeSuccess, commitMsg, nil)
+		PatchRunReport.Finish(origin.PatchOutcomeSuccess, 0, nil)
+	}
 }
 
-func definePatchFiles(args []string) {
-	log.Debug("subcmd.definePatchFiles()")
-	srcDir := filepath.Join(origin.OriginRepo.RepoDir, "src")
-	for index := range args {
-		foundFiles, foundErr := utils.FindFilesInDir(args[index], origin.OriginRepo.RepoDir)
-		foundFiles2, foundErr2 := utils.FindFilesInDirRecursively(args[index], srcDir)
-		foundFiles = append(foundFiles, foundFiles2...)
-		if foundErr != nil || foundErr2 != nil {
-			fmt.Fprintf(os.Stderr, "%s %s", foundErr, foundErr2)
-			os.Exit(1)
-		}
-		if len(foundFiles) == 0 {
-			fmt.Fprintf(os.Stderr, "No files found with name %s", args[index])
-			os.Exit(1)
-		}
-		if len(foundFiles) > 1 {
-			errorMsg := "Error: Multiple files found:\n"
-			for _, file := range foundFiles {
-				errorMsg += fmt.Sprintf("  - %s\n", file)
-			}
-			fmt.Fprintf(os.Stderr, "%s", errorMsg)
-			os.Exit(1)
+// The outcome of a patch run that failed with err
+func failureOutcome(err error) string {
+	if errors.Is(err, utils.ErrInterrupted) {
+		return origin.PatchOutcomeInterrupted
+	}
+	return origin.PatchOutcomeFailure
+}
+
+// Appends an entry for this patch run to the patch history of the distribution. commitMsg is the commit message
+// of this run, also if it hasn't been written to editorConfig.json (yet).
+func recordPatchHistory(outcome string, commitMsg string, runErr error) {
+	log.Debug("subcmd.recordPatchHistory()")
+	distribution := origin.OriginRepo.GetDistribution(DistributionNameFlag)
+	entry := origin.PatchHistoryEntry{
+		Timestamp:    time.Now(),
+		Distribution: DistributionNameFlag,
+		Target:       PatchTargetFlag,
+		Files:        []origin.PatchedFile{},
+		CommitMsg:    commitMsg,
+		CLIVersion:   divekit.Version,
+		DryRun:       utils.DryRunFlag,
+		Preview:      PreviewFlag,
+		Outcome:      outcome,
+	}
+	if runErr != nil {
+		entry.Error = runErr.Error()
+	}
+	for _, patchFile := range PatchFiles {
+		hash, err := utils.HashFile(filepath.Join(origin.OriginRepo.RepoDir, patchFile))
+		if err != nil {
+			log.Warnf("Could not hash %s for the patch history: %v", patchFile, err)
 		}
-		log.Debug(fmt.Sprintf("Found file %s", foundFiles[0]))
-		relFile, err := utils.TransformIntoRelativePaths(origin.OriginRepo.RepoDir, foundFiles[0])
-		log.Debug(fmt.Sprintf("... relative to origin repo: %s", relFile))
+		entry.Files = append(entry.Files, origin.PatchedFile{Path: filepath.ToSlash(patchFile), SHA256: hash})
+	}
+	originCommit, err := utils.GitHeadCommit(origin.OriginRepo.RepoDir)
+	if err != nil {
+		log.Warnf("Could not determine the origin commit for the patch history: %v", err)
+	}
+	entry.OriginCommit = originCommit
+
+	err = writeWithRunLock("patch", func() error {
+		return distribution.PatchHistoryFile.AppendEntry(entry)
+	})
+	if err != nil {
+		log.Errorf("Could not record the patch run in the patch history: %v", err)
+		return
+	}
+	log.Info("Recorded the patch run in " + distribution.PatchHistoryFile.FilePath)
+}
+
+// Renders the commit message for the patch from the --message or --message-file flag, or else from
+// the default template in the origin repo's CLI settings
+func defineCommitMsg() string {
+	log.Debug("subcmd.defineCommitMsg()")
+	commitMsgTemplate := CommitMsgFlag
+	if CommitMsgFileFlag != "" {
+		content, err := os.ReadFile(CommitMsgFileFlag)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "%s", err)
-			os.Exit(1)
+			utils.OutputAndAbortIfError(utils.NewReadError(err, "Error reading commit message file: %v", err))
 		}
-		PatchFiles = append(PatchFiles, relFile)
+		commitMsgTemplate = string(content)
+	}
+	if commitMsgTemplate == "" {
+		commitMsgTemplate = origin.OriginRepo.CLISettingsFile.Content.Patch.CommitMessageTemplate
 	}
+	if commitMsgTemplate == "" {
+		commitMsgTemplate = patch.DefaultCommitMsgTemplate
+	}
+	commitMsg, err := patch.RenderCommitMsg(commitMsgTemplate,
+		patch.NewCommitMsgData(DistributionNameFlag, PatchFiles))
+	if err != nil {
+		utils.AbortWithError(utils.ErrUsage, "Error defining the commit message: %v", err)
+	}
+	log.Info("Commit message for the patch: " + commitMsg)
+	return commitMsg
 }
 
-func setRepositoryConfigWithinARSRepo() {
+func setRepositoryConfigWithinARSRepo() *ars.RepositoryConfigFileType {
 	log.Debug("subcmd.setRepositoryConfigWithinARSRepo()")
-	distribution := origin.OriginRepo.GetDistribution(DistributionNameFlag)
-	if distribution == nil {
-		log.WithFields(log.Fields{
-			"DistributionNameFlag": DistributionNameFlag,
-		}).Fatal("Distribution not found")
-		os.Exit(1)
+	distribution := getDistributionOrFail(DistributionNameFlag)
+	repositoryConfigWithinARSRepo := cloneRepositoryConfigIntoARSRepo(distribution, ars.ValidateForLocalMode)
+	if MemberFilter != nil && MemberFilter.IsActive() {
+		repository := &repositoryConfigWithinARSRepo.Content.Repository
+		unmatched := MemberFilter.UnmatchedOnlyIds(repository.RepositoryMembers)
+		if len(unmatched) > 0 {
+			log.Warn("These campus IDs are not members of distribution " + DistributionNameFlag + ": " +
+				strings.Join(unmatched, ", "))
+		}
+		repository.RepositoryMembers = MemberFilter.FilterRepositoryMembers(repository.RepositoryMembers)
+		if len(repository.RepositoryMembers) == 0 {
+			utils.AbortWithError(utils.ErrUsage, "No repository of distribution %s is left after filtering the members",
+				DistributionNameFlag)
+		}
+		log.Info(fmt.Sprintf("Restricting the patch to the repos of %d member group(s)",
+			len(repository.RepositoryMembers)))
+	}
+	repositoryConfigWithinARSRepo.Content.Local.SubsetPaths = PatchFiles
+	repositoryConfigWithinARSRepo.Content.General.LocalMode = true
+	if err := repositoryConfigWithinARSRepo.WriteContent(); err != nil {
+		utils.AbortWithError(utils.ErrIO, "Error writing repositoryConfig.json within the ARS repo: %v", err)
 	}
+	return repositoryConfigWithinARSRepo
+}
+
+// Validates the repositoryConfig.json of a distribution for the given mode, clones it into the ARS repo,
+// and sets the values that are the same for all commands. The caller still has to adapt and write the content.
+func cloneRepositoryConfigIntoARSRepo(distribution *origin.Distribution,
+	mode ars.ValidationMode) *ars.RepositoryConfigFileType {
+	log.Debug("subcmd.cloneRepositoryConfigIntoARSRepo()")
 	repositoryConfigFile := distribution.RepositoryConfigFile
-	repositoryConfigFile.ReadContent()
+	validateRepositoryConfigOrFail(repositoryConfigFile, mode)
+	if err := repositoryConfigFile.ParseContent(); err != nil {
+		utils.OutputAndAbortIfError(fmt.Errorf("Error reading the config of the distribution: %w", err))
+	}
 	repositoryConfigWithinARSRepo :=
 		repositoryConfigFile.CloneToDifferentLocation(ARSRepo.Config.RepositoryConfigFile.FilePath)
-	repositoryConfigWithinARSRepo.Content.Local.SubsetPaths = PatchFiles
-	repositoryConfigWithinARSRepo.Content.IndividualRepositoryPersist.UseSavedIndividualRepositories = true
-
-	individualConfigFile :=
-		filepath.Base(origin.OriginRepo.DistributionMap[DistributionNameFlag].IndividualizationConfigFileName)
+	repositoryConfigWithinARSRepo.Content.IndividualRepositoryPersist.UseSavedIndividualRepositories =
+		distribution.IndividualizationConfigFileName != ""
 	repositoryConfigWithinARSRepo.Content.IndividualRepositoryPersist.SavedIndividualRepositoriesFileName =
-		individualConfigFile
-	repositoryConfigWithinARSRepo.Content.General.LocalMode = true
+		filepath.Base(distribution.IndividualizationConfigFileName)
 	repositoryConfigWithinARSRepo.Content.General.GlobalLogLevel = utils.LogLevelAsString()
-	repositoryConfigWithinARSRepo.WriteContent()
+	// a relative origin path is meant relative to the ARS repo in the home dir, not to a run workspace
+	local := &repositoryConfigWithinARSRepo.Content.Local
+	if local.OriginRepositoryFilePath != "" && !filepath.IsAbs(local.OriginRepositoryFilePath) {
+		local.OriginRepositoryFilePath =
+			filepath.Join(divekit.DivekitHomeDir, ars.RepoName, local.OriginRepositoryFilePath)
+	}
+	return repositoryConfigWithinARSRepo
 }
 
-func copySavedIndividualizationFileToARS() {
+func copySavedIndividualizationFileToARS(distribution *origin.Distribution) {
 	log.Debug("subcmd.copySavedIndividualRepositoriesFileToARS()")
-	err := utils.CopyFile(origin.OriginRepo.DistributionMap[DistributionNameFlag].IndividualizationConfigFileName,
-		ARSRepo.IndividualizationConfig.Dir)
+	if MemberFilter != nil && MemberFilter.IsActive() {
+		keptCount, err := MemberFilter.FilterIndividualRepositoriesFile(
+			distribution.IndividualizationConfigFileName, ARSRepo.IndividualizationConfig.Dir)
+		if err != nil {
+			utils.OutputAndAbortIfError(fmt.Errorf("Error filtering individualization file into %s: %w",
+				ARSRepo.IndividualizationConfig.Dir, utils.ClassifyError(utils.ErrIO, err)))
+		}
+		log.Info(fmt.Sprintf("Kept %d individual repositories after filtering the members", keptCount))
+		return
+	}
+	err := utils.CopyFile(distribution.IndividualizationConfigFileName, ARSRepo.IndividualizationConfig.Dir)
 	if err != nil {
-		log.Fatalf("Error copying individualization file to %s: %v", ARSRepo.IndividualizationConfig.Dir, err)
+		utils.AbortWithError(utils.ErrIO, "Error copying individualization file to %s: %v",
+			ARSRepo.IndividualizationConfig.Dir, err)
 	}
 }
 
@@ -155,8 +351,7 @@ func copyLocallyGeneratedFilesToPatchTool() {
 		err = utils.CopyAllFilesInDir(ARSRepo.GeneratedLocalOutput.Dir, PatchRepo.InputDir)
 	}
 	if err != nil {
-		log.Fatalf("Error copying locally generated files to patch tool: %v", err)
-		os.Exit(1)
+		utils.AbortWithError(utils.ErrIO, "Error copying locally generated files to patch tool: %v", err)
 	}
 	log.Info("Copying completed.")
 }
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"divekit-cli/divekit/origin"
+	"divekit-cli/utils"
+	"fmt"
+	"github.com/apex/log"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Resolves the command line arguments into PatchFiles (relative to the origin repo). An argument can be
+//   - a glob pattern, where "**" matches any number of directories (e.g. src/test/java/**/E2*Tests.java),
+//   - a path containing a separator, relative to the origin repo or absolute within it, pointing to a file or
+//     to a directory (all files within are taken),
+//   - a bare file name, which is searched in the search roots of the origin repo's CLI settings
+//     (by default the origin repo root, and recursively under src).
+//
+// Paths excluded by the CLI settings are skipped when searching or walking directories, and rejected when they
+// are given explicitly.
+func definePatchFiles(args []string) {
+	log.Debug("subcmd.definePatchFiles()")
+	for _, arg := range args {
+		var matchedFiles []string
+		var err error
+		switch {
+		case utils.ContainsGlobMeta(arg):
+			matchedFiles, err = findPatchFilesByGlob(arg)
+		case isPathArg(arg):
+			matchedFiles, err = findPatchFilesByPath(arg)
+		default:
+			matchedFiles, err = findPatchFilesByName(arg)
+		}
+		utils.OutputAndAbortIfError(err)
+		if len(matchedFiles) == 0 {
+			utils.AbortWithError(utils.ErrNotFound, "No files found matching %s", arg)
+		}
+		log.Info(fmt.Sprintf("%s matched:\n  %s", arg, strings.Join(matchedFiles, "\n  ")))
+		addPatchFiles(matchedFiles)
+	}
+}
+
+func addPatchFiles(relFiles []string) {
+	for _, relFile := range relFiles {
+		if !containsString(PatchFiles, relFile) {
+			PatchFiles = append(PatchFiles, relFile)
+		}
+	}
+}
+
+func containsString(list []string, value string) bool {
+	for _, element := range list {
+		if element == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether the argument is a path rather than a bare file name. A bare name is always searched, even if a file
+// with that name exists at the origin repo root.
+func isPathArg(arg string) bool {
+	return strings.ContainsRune(arg, '/') || strings.ContainsRune(arg, filepath.Separator)
+}
+
+// Returns the path of the argument relative to the origin repo, or an error of class utils.ErrUsage if it
+// points outside of the origin repo
+func relPathInOriginRepo(arg string) (string, error) {
+	relPath := filepath.Clean(arg)
+	if filepath.IsAbs(relPath) {
+		var err error
+		relPath, err = filepath.Rel(origin.OriginRepo.RepoDir, relPath)
+		if err != nil {
+			return "", utils.NewError(utils.ErrUsage, "%s is not within the origin repo %s", arg,
+				origin.OriginRepo.RepoDir)
+		}
+	}
+	if relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", utils.NewError(utils.ErrUsage, "%s is not within the origin repo %s", arg,
+			origin.OriginRepo.RepoDir)
+	}
+	return relPath, nil
+}
+
+// Whether the path (relative to the origin repo) or one of its parent dirs is excluded by the CLI settings
+func isExcludedInOriginRepo(relPath string, isDir bool) bool {
+	cliSettingsFile := origin.OriginRepo.CLISettingsFile
+	slashPath := filepath.ToSlash(relPath)
+	segments := strings.Split(slashPath, "/")
+	for i := 1; i < len(segments); i++ {
+		if cliSettingsFile.IsExcluded(strings.Join(segments[:i], "/"), true) {
+			return true
+		}
+	}
+	return cliSettingsFile.IsExcluded(slashPath, isDir)
+}
+
+func findPatchFilesByGlob(pattern string) ([]string, error) {
+	log.Debug("subcmd.findPatchFilesByGlob() - pattern: " + pattern)
+	pattern = filepath.ToSlash(filepath.Clean(pattern))
+	matchedFiles := []string{}
+	err := walkOriginRepoFiles(origin.OriginRepo.RepoDir, func(relFile string) error {
+		matched, err := utils.MatchDoublestar(pattern, filepath.ToSlash(relFile))
+		if err != nil {
+			return utils.NewError(utils.ErrUsage, "Invalid pattern %s: %v", pattern, err)
+		}
+		if matched {
+			matchedFiles = append(matchedFiles, relFile)
+		}
+		return nil
+	})
+	return matchedFiles, err
+}
+
+func findPatchFilesByPath(arg string) ([]string, error) {
+	log.Debug("subcmd.findPatchFilesByPath() - arg: " + arg)
+	relPath, err := relPathInOriginRepo(arg)
+	if err != nil {
+		return nil, err
+	}
+	fullPath := filepath.Join(origin.OriginRepo.RepoDir, relPath)
+	fileInfo, err := os.Stat(fullPath)
+	if err != nil {
+		return []string{}, nil
+	}
+	if isExcludedInOriginRepo(relPath, fileInfo.IsDir()) {
+		return nil, utils.NewError(utils.ErrUsage, "%s is excluded by the excludePatterns in the CLI settings", arg)
+	}
+	if !fileInfo.IsDir() {
+		return []string{relPath}, nil
+	}
+	matchedFiles := []string{}
+	err = walkOriginRepoFiles(fullPath, func(relFile string) error {
+		matchedFiles = append(matchedFiles, filepath.Join(relPath, relFile))
+		return nil
+	})
+	return matchedFiles, err
+}
+
+func findPatchFilesByName(fileName string) ([]string, error) {
+	log.Debug("subcmd.findPatchFilesByName() - fileName: " + fileName)
+	relFiles := []string{}
+	for _, searchRoot := range origin.OriginRepo.CLISettingsFile.Content.Patch.SearchRoots {
+		searchRootDir := filepath.Join(origin.OriginRepo.RepoDir, filepath.FromSlash(searchRoot.Path))
+		if utils.ValidateDirPath(searchRootDir) != nil {
+			log.Debug("Skipping non-existing search root " + searchRootDir)
+			continue
+		}
+		var foundFiles []string
+		var err error
+		if searchRoot.Recursive {
+			err = walkOriginRepoFiles(searchRootDir, func(relFile string) error {
+				if filepath.Base(relFile) == fileName {
+					foundFiles = append(foundFiles, filepath.Join(searchRootDir, relFile))
+				}
+				return nil
+			})
+		} else {
+			foundFiles, err = utils.FindFilesInDir(fileName, searchRootDir)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, foundFile := range foundFiles {
+			log.Debug(fmt.Sprintf("Found file %s", foundFile))
+			relFile, err := utils.TransformIntoRelativePaths(origin.OriginRepo.RepoDir, foundFile)
+			if err != nil {
+				return nil, err
+			}
+			if !containsString(relFiles, relFile) {
+				relFiles = append(relFiles, relFile)
+			}
+		}
+	}
+	if len(relFiles) > 1 {
+		return chooseAmongMultipleMatches(fileName, relFiles)
+	}
+	return relFiles, nil
+}
+
+// Decides which of several files matching the same name are patched, depending on the --multiple flag
+func chooseAmongMultipleMatches(fileName string, relFiles []string) ([]string, error) {
+	switch MultipleMatchesFlag {
+	case "all":
+		return relFiles, nil
+	case "ask":
+		return utils.Choose(fmt.Sprintf("Multiple files found with name %s:", fileName), relFiles), nil
+	default:
+		errorMsg := "Multiple files found:\n"
+		for _, file := range relFiles {
+			errorMsg += fmt.Sprintf("  - %s\n", file)
+		}
+		errorMsg += "Use a relative path, or --multiple=all / --multiple=ask to choose."
+		return nil, utils.NewError(utils.ErrAmbiguous, "%s", errorMsg)
+	}
+}
+
+// Calls handleFile for every regular file below rootDir (with the path relative to rootDir),
+// skipping everything excluded by the CLI settings of the origin repo
+func walkOriginRepoFiles(rootDir string, handleFile func(relFile string) error) error {
+	cliSettingsFile := origin.OriginRepo.CLISettingsFile
+	return filepath.WalkDir(rootDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relToOrigin, err := filepath.Rel(origin.OriginRepo.RepoDir, path)
+		if err != nil {
+			return err
+		}
+		if relToOrigin != "." && cliSettingsFile.IsExcluded(filepath.ToSlash(relToOrigin), entry.IsDir()) {
+			log.Debug("Skipping excluded path " + relToOrigin)
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || !entry.Type().IsRegular() {
+			return nil
+		}
+		relFile, err := filepath.Rel(rootDir, path)
+		if err != nil {
+			return err
+		}
+		return handleFile(relFile)
+	})
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"divekit-cli/divekit/origin"
+	"divekit-cli/utils"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	// Flags
+	HistoryDistributionFlag string
+	HistoryFileFlag         string
+	HistoryOutcomeFlag      string
+	HistorySinceFlag        string
+	HistoryLimitFlag        int
+
+	patchHistoryCmd = &cobra.Command{
+		Use:   "history",
+		Short: "List the patch history of the origin repo",
+		Long: `List the recorded patch runs of all (or one) distribution of the origin repo, newest first.
+The entries can be filtered by distribution, patched file, outcome and date.`,
+		Args: cobra.NoArgs,
+		Run:  patchHistoryRun,
+	}
+)
+
+func init() {
+	log.Debug("patchHistory.init()")
+	patchHistoryCmd.Flags().StringVarP(&HistoryDistributionFlag, "distribution", "d", "",
+		"only list patch runs of this distribution (default: all distributions)")
+	patchHistoryCmd.Flags().StringVarP(&HistoryFileFlag, "file", "f", "",
+		"only list patch runs where a file containing this string was patched")
+	patchHistoryCmd.Flags().StringVar(&HistoryOutcomeFlag, "outcome", "",
+		"only list patch runs with this outcome (success, failure, interrupted, dry-run, preview)")
+	patchHistoryCmd.Flags().StringVar(&HistorySinceFlag, "since", "",
+		"only list patch runs on or after this date (YYYY-MM-DD)")
+	patchHistoryCmd.Flags().IntVarP(&HistoryLimitFlag, "limit", "n", 0,
+		"maximum number of patch runs to list (0 = no limit)")
+	patchCmd.AddCommand(patchHistoryCmd)
+}
+
+func patchHistoryRun(cmd *cobra.Command, args []string) {
+	log.Debug("patchHistory.run()")
+	if origin.OriginRepo == nil {
+		utils.AbortWithError(utils.ErrUsage, "You need to specify an origin repo with -o / --originrepo")
+	}
+	var since time.Time
+	if HistorySinceFlag != "" {
+		var err error
+		since, err = time.ParseInLocation("2006-01-02", HistorySinceFlag, time.Local)
+		if err != nil {
+			utils.AbortWithError(utils.ErrUsage, "Invalid date for --since: %s (expected YYYY-MM-DD)", HistorySinceFlag)
+		}
+	}
+
+	entries := readPatchHistoryEntries()
+	filteredEntries := []origin.PatchHistoryEntry{}
+	for _, entry := range entries {
+		if matchesHistoryFilters(entry, since) {
+			filteredEntries = append(filteredEntries, entry)
+		}
+	}
+	sort.SliceStable(filteredEntries, func(i, j int) bool {
+		return filteredEntries[i].Timestamp.After(filteredEntries[j].Timestamp)
+	})
+	if HistoryLimitFlag > 0 && len(filteredEntries) > HistoryLimitFlag {
+		filteredEntries = filteredEntries[:HistoryLimitFlag]
+	}
+	printPatchHistory(filteredEntries)
+}
+
+func readPatchHistoryEntries() []origin.PatchHistoryEntry {
+	log.Debug("patchHistory.readPatchHistoryEntries()")
+	entries := []origin.PatchHistoryEntry{}
+	for distributionName, distribution := range origin.OriginRepo.DistributionMap {
+		if HistoryDistributionFlag != "" && distributionName != HistoryDistributionFlag {
+			continue
+		}
+		distributionEntries, err := distribution.PatchHistoryFile.ReadEntries()
+		if err != nil {
+			utils.OutputAndAbortIfError(fmt.Errorf("Error reading the patch history of distribution %s: %w",
+				distributionName, err))
+		}
+		entries = append(entries, distributionEntries...)
+	}
+	if HistoryDistributionFlag != "" {
+		getDistributionOrFail(HistoryDistributionFlag)
+	}
+	return entries
+}
+
+func matchesHistoryFilters(entry origin.PatchHistoryEntry, since time.Time) bool {
+	if HistoryOutcomeFlag != "" && entry.Outcome != HistoryOutcomeFlag {
+		return false
+	}
+	if !since.IsZero() && entry.Timestamp.Before(since) {
+		return false
+	}
+	if HistoryFileFlag != "" {
+		for _, file := range entry.Files {
+			if strings.Contains(file.Path, HistoryFileFlag) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func printPatchHistory(entries []origin.PatchHistoryEntry) {
+	if len(entries) == 0 {
+		fmt.Println("No patch runs found.")
+		return
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "TIMESTAMP\tDISTRIBUTION\tOUTCOME\tORIGIN COMMIT\tCOMMIT MESSAGE\tFILES")
+	for _, entry := range entries {
+		files := make([]string, 0, len(entry.Files))
+		for _, file := range entry.Files {
+			files = append(files, file.Path)
+		}
+		originCommit := entry.OriginCommit
+		if len(originCommit) > 8 {
+			originCommit = originCommit[:8]
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n", entry.Timestamp.Local().Format("2006-01-02 15:04"),
+			entry.Distribution, entry.Outcome, originCommit, entry.CommitMsg, strings.Join(files, ", "))
+	}
+	writer.Flush()
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"divekit-cli/divekit/origin"
+	"divekit-cli/utils"
+	"encoding/hex"
+	"fmt"
+	"github.com/apex/log"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// one individualized version of a patch file, as generated by the ARS for a single repo
+type generatedPatchFile struct {
+	RepoName     string
+	PatchFile    string
+	FullPath     string
+	ContentHash  string
+	ContentBytes []byte
+}
+
+// Prints a unified diff between each locally generated patch file and its counterpart in the
+// origin repo, followed by a summary of how many repos get which variant of each file.
+func previewGeneratedFiles() {
+	log.Debug("subcmd.previewGeneratedFiles()")
+	generatedFiles, err := findGeneratedPatchFiles(ARSRepo.GeneratedLocalOutput.Dir)
+	utils.OutputAndAbortIfError(err)
+	if len(generatedFiles) == 0 {
+		log.Warn("The ARS did not generate any of the patch files in " + ARSRepo.GeneratedLocalOutput.Dir)
+		return
+	}
+
+	originContents := make(map[string]string)
+	for _, patchFile := range PatchFiles {
+		content, err := os.ReadFile(filepath.Join(origin.OriginRepo.RepoDir, patchFile))
+		utils.OutputAndAbortIfError(err)
+		originContents[patchFile] = string(content)
+	}
+
+	for _, generatedFile := range generatedFiles {
+		diff := utils.UnifiedDiff(
+			filepath.ToSlash(filepath.Join("origin", generatedFile.PatchFile)),
+			filepath.ToSlash(filepath.Join(generatedFile.RepoName, generatedFile.PatchFile)),
+			originContents[generatedFile.PatchFile], string(generatedFile.ContentBytes))
+		if diff == "" {
+			fmt.Printf("=== %s: %s is identical to the origin repo\n", generatedFile.RepoName, generatedFile.PatchFile)
+			continue
+		}
+		fmt.Printf("=== %s: %s\n%s", generatedFile.RepoName, generatedFile.PatchFile, diff)
+	}
+	printVariantSummary(generatedFiles)
+}
+
+// Walks the local ARS output and collects every file that corresponds to one of the PatchFiles.
+// The part of the path in front of the patch file's relative path identifies the repo.
+func findGeneratedPatchFiles(outputDir string) ([]generatedPatchFile, error) {
+	log.Debug("subcmd.findGeneratedPatchFiles()")
+	generatedFiles := []generatedPatchFile{}
+	err := filepath.WalkDir(outputDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(outputDir, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		for _, patchFile := range PatchFiles {
+			patchFileSlashed := filepath.ToSlash(patchFile)
+			if !strings.HasSuffix(relPath, "/"+patchFileSlashed) {
+				continue
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			hash := sha256.Sum256(content)
+			generatedFiles = append(generatedFiles, generatedPatchFile{
+				RepoName:     strings.TrimSuffix(relPath, "/"+patchFileSlashed),
+				PatchFile:    patchFile,
+				FullPath:     path,
+				ContentHash:  hex.EncodeToString(hash[:])[:8],
+				ContentBytes: content,
+			})
+			break
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error walking the generated files in %s: %v", outputDir, err)
+	}
+	return generatedFiles, nil
+}
+
+func printVariantSummary(generatedFiles []generatedPatchFile) {
+	log.Debug("subcmd.printVariantSummary()")
+	fmt.Println("\n=== Summary of variants")
+	for _, patchFile := range PatchFiles {
+		reposByVariant := make(map[string][]string)
+		for _, generatedFile := range generatedFiles {
+			if generatedFile.PatchFile == patchFile {
+				reposByVariant[generatedFile.ContentHash] = append(reposByVariant[generatedFile.ContentHash],
+					generatedFile.RepoName)
+			}
+		}
+		variants := make([]string, 0, len(reposByVariant))
+		for variant := range reposByVariant {
+			variants = append(variants, variant)
+		}
+		sort.Slice(variants, func(i, j int) bool {
+			return len(reposByVariant[variants[i]]) > len(reposByVariant[variants[j]])
+		})
+		fmt.Printf("%s: %d variant(s)\n", patchFile, len(variants))
+		for _, variant := range variants {
+			fmt.Printf("  - variant %s: %d repo(s)\n", variant, len(reposByVariant[variant]))
+		}
+	}
+}
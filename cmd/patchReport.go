@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"divekit-cli/divekit"
+	"divekit-cli/divekit/runreport"
+	"divekit-cli/utils"
+	"fmt"
+	"github.com/apex/log"
+	"path/filepath"
+	"sort"
+)
+
+var (
+	// Flags
+	ReportFlag         string
+	ReportMarkdownFlag string
+	// command state vars
+	PatchRunReport *runreport.RunReportType
+)
+
+// Starts the report of the patch run, which collects all warnings from now on (those in the output of the tools
+// separately). If --report or
+// --report-markdown is given, the report is written at the end of the run - successful or not.
+func startPatchRunReport() {
+	log.Debug("subcmd.startPatchRunReport()")
+	PatchRunReport = runreport.NewRunReport("patch", divekit.Version)
+	content := &PatchRunReport.Content
+	content.Distribution = DistributionNameFlag
+	content.Target = PatchTargetFlag
+	content.DryRun = utils.DryRunFlag
+	content.Preview = PreviewFlag
+	utils.AddLogListener(func(entry *log.Entry) {
+		if entry.Level < log.WarnLevel {
+			return
+		}
+		if utils.IsToolOutputMessage(entry.Message) {
+			PatchRunReport.AddToolWarning(entry.Message)
+		} else {
+			PatchRunReport.AddWarning(entry.Message)
+		}
+	})
+	utils.AddPhaseListener(func(phase string) {
+		if exitCode, _ := utils.ExitReason(); phase == utils.CleanupPhase && exitCode != 0 {
+			// the run is aborted during the current phase
+			PatchRunReport.EndPhase(runreport.PhaseFailed)
+		}
+		PatchRunReport.StartPhase(phase)
+	})
+	if ReportFlag != "" || ReportMarkdownFlag != "" {
+		utils.RegisterCleanup(writePatchRunReport)
+	}
+}
+
+// Records the repos that the ARS generated in its local output, e.g. "code/st2-m3-1a2b"
+func recordGeneratedRepos() {
+	log.Debug("subcmd.recordGeneratedRepos()")
+	repoNames := []string{}
+	outputDir := ARSRepo.GeneratedLocalOutput.Dir
+	kindDirs, err := utils.ListSubfolderNames(outputDir)
+	if err != nil {
+		log.Warnf("Could not list the repos generated in %s: %v", outputDir, err)
+	}
+	for _, kindDir := range kindDirs {
+		repoDirs, err := utils.ListSubfolderNames(filepath.Join(outputDir, kindDir))
+		if err != nil {
+			log.Warnf("Could not list the repos generated in %s: %v", filepath.Join(outputDir, kindDir), err)
+			continue
+		}
+		for _, repoDir := range repoDirs {
+			repoNames = append(repoNames, kindDir+"/"+repoDir)
+		}
+	}
+	sort.Strings(repoNames)
+	PatchRunReport.SetGeneratedRepos(repoNames, runreport.RepoNotPatched)
+	log.Info(fmt.Sprintf("The ARS generated %d repo(s)", len(repoNames)))
+}
+
+func writePatchRunReport() {
+	log.Debug("subcmd.writePatchRunReport()")
+	// the cleanup phase, which is ended here as this is the last cleanup
+	PatchRunReport.EndPhase(runreport.PhaseOK)
+	PatchRunReport.Content.InterruptedPhase = utils.InterruptedPhase()
+	if PatchRunReport.Content.Outcome == "" {
+		exitCode, err := utils.ExitReason()
+		PatchRunReport.Finish(failureOutcome(err), exitCode, err)
+	}
+	if ReportFlag != "" {
+		if err := PatchRunReport.WriteJSON(ReportFlag); err != nil {
+			log.Errorf("%v", err)
+		} else {
+			log.Info("Wrote the run report " + ReportFlag)
+		}
+	}
+	if ReportMarkdownFlag != "" {
+		if err := PatchRunReport.WriteMarkdown(ReportMarkdownFlag); err != nil {
+			log.Errorf("%v", err)
+		} else {
+			log.Info("Wrote the run report " + ReportMarkdownFlag)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"divekit-cli/divekit/snapshot"
+	"divekit-cli/utils"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	restoreCmd = &cobra.Command{
+		Use:   "restore",
+		Short: "Restore the ARS and Repo Editor configs after a crashed run",
+		Long: `Every run that changes the configs of the ARS or the Repo Editor saves a snapshot of them before,
+and restores it afterwards. If a run crashed before it could restore the snapshot, this command does it.`,
+		Args: cobra.NoArgs,
+		Run:  restoreRun,
+	}
+)
+
+func init() {
+	log.Debug("restore.init()")
+	rootCmd.AddCommand(restoreCmd)
+}
+
+func restoreRun(cmd *cobra.Command, args []string) {
+	log.Debug("restore.run()")
+	acquireRunLock("restore")
+	latestSnapshot, err := snapshot.LoadLatestSnapshot()
+	utils.OutputAndAbortIfError(err)
+	if latestSnapshot == nil {
+		log.Info("There is no snapshot to restore.")
+		return
+	}
+	log.Info(fmt.Sprintf("Restoring the snapshot taken by '%s' on %s",
+		latestSnapshot.Content.Command, latestSnapshot.Content.CreatedAt.Format("2006-01-02 15:04:05")))
+	utils.OutputAndAbortIfError(latestSnapshot.Restore())
+}
+
+// Saves the configs and input dirs of the ARS (and the Repo Editor, if used) that the command is about
+// to change, and registers a cleanup that restores them at the end of the run - successful or not.
+func snapshotToolConfigs(commandName string) {
+	log.Debug("subcmd.snapshotToolConfigs()")
+	previousSnapshot, err := snapshot.LoadLatestSnapshot()
+	utils.OutputAndAbortIfError(err)
+	if previousSnapshot != nil {
+		utils.AbortWithError(utils.ErrLocked, "There is an unrestored snapshot from a previous run "+
+			"('%s' on %s) in %s.\nPlease run 'divekit restore' first.", previousSnapshot.Content.Command,
+			previousSnapshot.Content.CreatedAt.Format("2006-01-02 15:04:05"), previousSnapshot.Dir)
+	}
+
+	paths := []string{ARSRepo.Config.RepositoryConfigFile.FilePath, ARSRepo.IndividualizationConfig.Dir}
+	if PatchRepo != nil {
+		paths = append(paths, PatchRepo.PatchConfigFile.FilePath, PatchRepo.InputDir)
+	}
+	toolConfigSnapshot, err := snapshot.NewSnapshot(commandName, paths...)
+	utils.OutputAndAbortIfError(err)
+	utils.RegisterCleanup(func() {
+		err := toolConfigSnapshot.Restore()
+		if err != nil {
+			log.Errorf("Could not restore the tool configs, please run 'divekit restore': %v", err)
+		}
+	})
+}
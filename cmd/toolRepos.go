@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"divekit-cli/divekit"
+	"divekit-cli/divekit/ars"
+	"divekit-cli/divekit/patch"
+	"divekit-cli/divekit/workspace"
+	"divekit-cli/utils"
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"path/filepath"
+	"time"
+)
+
+// Adds the --timeout flag to a command that runs the tools
+func addTimeoutFlag(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&utils.PhaseTimeoutFlags, "timeout", nil,
+		"stop the run if a phase takes longer: a duration for every phase (e.g. 30m), or <phase>=<duration>")
+}
+
+// Starts the next phase of the run, or aborts if the run has been interrupted in the meantime
+func startPhase(phase string) {
+	utils.OutputAndAbortIfError(utils.StartPhase(phase))
+}
+
+// Prepares the tool repos (ARS, and Repo Editor if used) before a command changes their configs. By default,
+// ARSRepo and PatchRepo are re-pointed to copies in a fresh run workspace, which is removed at the end of
+// the run. With --in-place, the tool repos themselves are used, protected by the run lock and a snapshot of
+// their configs. Runs in workspaces don't share the tool repos, so they only need the run lock for what they
+// write into the origin repo.
+func prepareToolRepos(commandName string) {
+	log.Debug("subcmd.prepareToolRepos()")
+	openToolLogFile(commandName)
+	if InPlaceFlag {
+		acquireRunLock(commandName)
+		snapshotToolConfigs(commandName)
+		return
+	}
+
+	toolRepoDirs := []string{ARSRepo.RepoDir}
+	if PatchRepo != nil {
+		toolRepoDirs = append(toolRepoDirs, PatchRepo.RepoDir)
+	}
+	generatedLocalOutputDir, err := filepath.Rel(ARSRepo.RepoDir, ARSRepo.GeneratedLocalOutput.Dir)
+	utils.OutputAndAbortIfError(err)
+	runWorkspace, err := workspace.NewWorkspace(toolRepoDirs, []string{generatedLocalOutputDir})
+	utils.OutputAndAbortIfError(err)
+	utils.RegisterCleanup(func() {
+		err := runWorkspace.Remove()
+		if err != nil {
+			log.Warnf("%v", err)
+		}
+	})
+
+	ARSRepo, err = ars.NewARSRepoInDir(runWorkspace.ToolRepoDir(ARSRepo.RepoDir))
+	utils.OutputAndAbortIfError(err)
+	if PatchRepo != nil {
+		PatchRepo, err = patch.NewPatchRepoInDir(runWorkspace.ToolRepoDir(PatchRepo.RepoDir))
+		utils.OutputAndAbortIfError(err)
+	}
+}
+
+// Opens the tool log file of this run, to which the full output of the ARS and Repo Editor is written, e.g.
+// $DIVEKIT_HOME/.divekit-cli/logs/20230504-101112-patch.log. A run without it is still possible.
+func openToolLogFile(commandName string) {
+	log.Debug("subcmd.openToolLogFile()")
+	logFilePath := filepath.Join(divekit.DivekitHomeDir, ".divekit-cli", "logs",
+		time.Now().Format("20060102-150405")+"-"+commandName+".log")
+	if err := utils.OpenToolLogFile(logFilePath); err != nil {
+		log.Warnf("The output of the tools is not saved: %v", err)
+		return
+	}
+	utils.RegisterCleanup(utils.CloseToolLogFile)
+	log.Info("The full output of the tools is saved in " + logFilePath)
+}
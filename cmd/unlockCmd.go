@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"divekit-cli/divekit/runlock"
+	"divekit-cli/utils"
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+// how long a run waits for another run to release the lock before it writes into the origin repo
+const runLockMaxWait = time.Minute
+
+var (
+	// Flags
+	UnlockForceFlag bool
+	// command state vars
+	heldRunLock *runlock.RunLockType // the lock, if this run holds it until its end
+
+	unlockCmd = &cobra.Command{
+		Use:   "unlock",
+		Short: "Remove the run lock of a crashed run",
+		Long: `Every command that changes something holds a lock in the Divekit home dir while it runs, so that two runs
+don't overwrite each other's configs. A lock of a run that doesn't run anymore is removed automatically
+if that can be detected (same host). Otherwise, this command removes it: without --force only if it is
+stale, with --force in any case.`,
+		Args: cobra.NoArgs,
+		Run:  unlockRun,
+	}
+)
+
+func init() {
+	log.Debug("unlock.init()")
+	unlockCmd.Flags().BoolVar(&UnlockForceFlag, "force", false,
+		"remove the lock even if its holder seems to be still running")
+	rootCmd.AddCommand(unlockCmd)
+}
+
+func unlockRun(cmd *cobra.Command, args []string) {
+	log.Debug("unlock.run()")
+	runLock, err := runlock.Load()
+	utils.OutputAndAbortIfError(err)
+	if runLock == nil {
+		log.Info("There is no run lock.")
+		return
+	}
+	stale, reason := runLock.IsStale()
+	if !stale && !UnlockForceFlag {
+		utils.AbortWithError(utils.ErrLocked, "The run lock is held by %s, which seems to be still running.\n"+
+			"If you are sure it isn't, run 'divekit unlock --force'.", runLock.Describe())
+	}
+	utils.OutputAndAbortIfError(runLock.Remove())
+	if stale {
+		log.Info("Removed the stale run lock of " + runLock.Describe() + " (" + reason + ")")
+	} else {
+		log.Warn("Removed the run lock of " + runLock.Describe())
+	}
+}
+
+// Acquires the run lock for a command that changes something, and registers a cleanup that releases it at
+// the end of the run - successful or not
+func acquireRunLock(commandName string) {
+	log.Debug("subcmd.acquireRunLock() - commandName: " + commandName)
+	runLock, err := runlock.Acquire(commandName)
+	utils.OutputAndAbortIfError(err)
+	heldRunLock = runLock
+	utils.RegisterCleanup(func() {
+		if err := runLock.Release(); err != nil {
+			log.Errorf("Could not release the run lock, please run 'divekit unlock': %v", err)
+		}
+	})
+}
+
+// Calls write while holding the run lock, e.g. for a run in a workspace to write into the origin repo. If this
+// run holds the lock anyway, write is just called. If another run holds it, waits up to runLockMaxWait for it.
+func writeWithRunLock(commandName string, write func() error) error {
+	log.Debug("subcmd.writeWithRunLock() - commandName: " + commandName)
+	if heldRunLock != nil {
+		return write()
+	}
+	runLock, err := runlock.AcquireWaiting(commandName, runLockMaxWait)
+	if err != nil {
+		return err
+	}
+	err = write()
+	if releaseErr := runLock.Release(); releaseErr != nil {
+		log.Errorf("Could not release the run lock, please run 'divekit unlock': %v", releaseErr)
+	}
+	return err
+}
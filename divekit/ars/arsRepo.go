@@ -11,6 +11,9 @@ import (
 	"path/filepath"
 )
 
+// name of the ARS repo, which must be cloned under this name into the Divekit home dir
+const RepoName = "divekit-automated-repo-setup"
+
 // all the paths used in the ARS repository (all as full paths)
 type ARSRepoType struct {
 	RepoDir string
@@ -31,20 +34,19 @@ type ARSRepoType struct {
 }
 
 // This method is similar to a constructor in OOP
-func NewARSRepo() *ARSRepoType {
+func NewARSRepo() (*ARSRepoType, error) {
 	log.Debug("ars.NewARSRepo()")
-	arsRepo := &ARSRepoType{}
-	arsRepo.RepoDir = filepath.Join(divekit.DivekitHomeDir, "divekit-automated-repo-setup")
-	arsRepo.Config.Dir = filepath.Join(arsRepo.RepoDir, "resources/config")
-	arsRepo.Config.RepositoryConfigFile =
-		NewRepositoryConfigFile(filepath.Join(arsRepo.Config.Dir, "repositoryConfig.json"))
-	arsRepo.IndividualizationConfig.Dir = filepath.Join(arsRepo.RepoDir, "resources/individual_repositories")
-	arsRepo.GeneratedOverviewFiles.Dir = filepath.Join(arsRepo.RepoDir, "resources/overview")
-	arsRepo.GeneratedLocalOutput.Dir = filepath.Join(arsRepo.RepoDir, "resources/test/output")
+	return NewARSRepoInDir(filepath.Join(divekit.DivekitHomeDir, RepoName))
+}
 
-	utils.OutputAndAbortIfErrors(
-		utils.ValidateAllDirPaths(arsRepo.RepoDir, arsRepo.Config.Dir, arsRepo.IndividualizationConfig.Dir,
-			arsRepo.GeneratedOverviewFiles.Dir, arsRepo.GeneratedLocalOutput.Dir))
+// Same as NewARSRepo, but for a copy of the ARS repo in a different directory (e.g. a run workspace)
+// Returns an error of class utils.ErrNotFound if a part of the repo is missing.
+func NewARSRepoInDir(repoDir string) (*ARSRepoType, error) {
+	log.Debug("ars.NewARSRepoInDir() - repoDir: " + repoDir)
+	arsRepo := newARSRepoLayoutInDir(repoDir)
+	if err := utils.JoinErrors(arsRepo.Validate()); err != nil {
+		return nil, err
+	}
 	log.WithFields(log.Fields{
 		"RepoDir":                      arsRepo.RepoDir,
 		"ConfigDir":                    arsRepo.Config.Dir,
@@ -53,5 +55,32 @@ func NewARSRepo() *ARSRepoType {
 		"GeneratedOverviewFilesDir":    arsRepo.GeneratedOverviewFiles.Dir,
 		"GeneratedLocalOutputFilesDir": arsRepo.GeneratedLocalOutput.Dir,
 	}).Info("Setting global variables:")
+	return arsRepo, nil
+}
+
+// Returns the expected layout of the ARS repo, without checking if it actually exists
+func NewARSRepoLayout() *ARSRepoType {
+	log.Debug("ars.NewARSRepoLayout()")
+	return newARSRepoLayoutInDir(filepath.Join(divekit.DivekitHomeDir, RepoName))
+}
+
+func newARSRepoLayoutInDir(repoDir string) *ARSRepoType {
+	arsRepo := &ARSRepoType{}
+	arsRepo.RepoDir = repoDir
+	arsRepo.Config.Dir = filepath.Join(arsRepo.RepoDir, "resources/config")
+	arsRepo.Config.RepositoryConfigFile = &RepositoryConfigFileType{
+		FilePath: filepath.Join(arsRepo.Config.Dir, "repositoryConfig.json"),
+	}
+	arsRepo.IndividualizationConfig.Dir = filepath.Join(arsRepo.RepoDir, "resources/individual_repositories")
+	arsRepo.GeneratedOverviewFiles.Dir = filepath.Join(arsRepo.RepoDir, "resources/overview")
+	arsRepo.GeneratedLocalOutput.Dir = filepath.Join(arsRepo.RepoDir, "resources/test/output")
 	return arsRepo
 }
+
+// Checks that all directories and files of the ARS repo exist, and returns all problems found
+func (arsRepo *ARSRepoType) Validate() []error {
+	log.Debug("ars.Validate()")
+	errorsList := utils.ValidateAllDirPaths(arsRepo.RepoDir, arsRepo.Config.Dir, arsRepo.IndividualizationConfig.Dir,
+		arsRepo.GeneratedOverviewFiles.Dir, arsRepo.GeneratedLocalOutput.Dir)
+	return append(errorsList, utils.ValidateAllFilePaths(arsRepo.Config.RepositoryConfigFile.FilePath)...)
+}
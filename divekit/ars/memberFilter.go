@@ -0,0 +1,156 @@
+package ars
+
+/**
+ * This file contains the filtering of repository members, used to restrict a run to a subset of the
+ * repositories of a distribution. A repository is identified by its members (campus IDs).
+ */
+
+import (
+	"divekit-cli/utils"
+	"encoding/json"
+	"fmt"
+	"github.com/apex/log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type MemberFilterType struct {
+	Only    map[string]bool // if not empty, only repositories with at least one of these members are kept
+	Exclude map[string]bool // repositories with any of these members are dropped
+}
+
+// This method is similar to a constructor in OOP
+func NewMemberFilter(only []string, exclude []string) *MemberFilterType {
+	log.Debug("ars.NewMemberFilter()")
+	memberFilter := &MemberFilterType{
+		Only:    make(map[string]bool),
+		Exclude: make(map[string]bool),
+	}
+	for _, campusId := range only {
+		memberFilter.Only[normalizeCampusId(campusId)] = true
+	}
+	for _, campusId := range exclude {
+		memberFilter.Exclude[normalizeCampusId(campusId)] = true
+	}
+	delete(memberFilter.Only, "")
+	delete(memberFilter.Exclude, "")
+	return memberFilter
+}
+
+// Reads campus IDs from a file, separated by newlines, commas, semicolons or whitespace.
+// Lines starting with "#" are ignored. Returns an error of class utils.ErrNotFound if the file doesn't exist.
+func ReadCampusIdsFromFile(filePath string) ([]string, error) {
+	log.Debug("ars.ReadCampusIdsFromFile() - filePath: " + filePath)
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, utils.NewReadError(err, "failed to read members file: %w", err)
+	}
+	campusIds := []string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ';' || r == ' ' || r == '\t' || r == '\r'
+		})
+		campusIds = append(campusIds, fields...)
+	}
+	return campusIds, nil
+}
+
+func normalizeCampusId(campusId string) string {
+	return strings.ToLower(strings.TrimSpace(campusId))
+}
+
+func (memberFilter *MemberFilterType) IsActive() bool {
+	return len(memberFilter.Only) > 0 || len(memberFilter.Exclude) > 0
+}
+
+// Checks if a repository with the given members passes the filter
+func (memberFilter *MemberFilterType) Keeps(members []string) bool {
+	keep := len(memberFilter.Only) == 0
+	for _, member := range members {
+		if memberFilter.Exclude[normalizeCampusId(member)] {
+			return false
+		}
+		if memberFilter.Only[normalizeCampusId(member)] {
+			keep = true
+		}
+	}
+	return keep
+}
+
+// Returns the campus IDs from the "only" list that don't occur in any of the given repositories
+func (memberFilter *MemberFilterType) UnmatchedOnlyIds(repositoryMembers [][]string) []string {
+	found := make(map[string]bool)
+	for _, members := range repositoryMembers {
+		for _, member := range members {
+			found[normalizeCampusId(member)] = true
+		}
+	}
+	unmatched := []string{}
+	for campusId := range memberFilter.Only {
+		if !found[campusId] {
+			unmatched = append(unmatched, campusId)
+		}
+	}
+	return unmatched
+}
+
+// Filters the repository members of the repositoryConfig.json content
+func (memberFilter *MemberFilterType) FilterRepositoryMembers(repositoryMembers [][]string) [][]string {
+	log.Debug("ars.FilterRepositoryMembers()")
+	filtered := [][]string{}
+	for _, members := range repositoryMembers {
+		if memberFilter.Keeps(members) {
+			filtered = append(filtered, members)
+		}
+	}
+	return filtered
+}
+
+// Writes a filtered copy of a saved individual_repositories file (with the same file name) into destDir.
+// The kept entries are copied as they are, with all their other content and the order of their keys.
+func (memberFilter *MemberFilterType) FilterIndividualRepositoriesFile(srcFilePath, destDir string) (int, error) {
+	log.Debug("ars.FilterIndividualRepositoriesFile() - srcFilePath: " + srcFilePath)
+	content, err := os.ReadFile(srcFilePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read individualization file: %v", err)
+	}
+	var individualRepositories []json.RawMessage
+	err = json.Unmarshal(content, &individualRepositories)
+	if err != nil {
+		return 0, fmt.Errorf("failed to unmarshal JSON in %s: %v", srcFilePath, err)
+	}
+
+	filtered := []json.RawMessage{}
+	for index, individualRepository := range individualRepositories {
+		// only read to find the members, the entry itself is written as it was read
+		var fields map[string]json.RawMessage
+		err = json.Unmarshal(individualRepository, &fields)
+		if err != nil {
+			return 0, fmt.Errorf("invalid entry %d in %s: %v", index, srcFilePath, err)
+		}
+		var members []string
+		if rawMembers, ok := fields["members"]; ok {
+			err = json.Unmarshal(rawMembers, &members)
+			if err != nil {
+				return 0, fmt.Errorf("invalid members in entry %d of %s: %v", index, srcFilePath, err)
+			}
+		}
+		if memberFilter.Keeps(members) {
+			filtered = append(filtered, individualRepository)
+		}
+	}
+
+	filteredContent, err := json.MarshalIndent(filtered, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal JSON: %v", err)
+	}
+	err = os.WriteFile(filepath.Join(destDir, filepath.Base(srcFilePath)), filteredContent, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write filtered individualization file: %v", err)
+	}
+	return len(filtered), nil
+}
@@ -0,0 +1,82 @@
+package ars
+
+import (
+	"divekit-cli/utils"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadCampusIdsFromFile(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "members.txt")
+	content := "# the repeaters\nab123, cd456;ef789\n\n  gh012\tij345\r\n# kl678\n"
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	campusIds, err := ReadCampusIdsFromFile(filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"ab123", "cd456", "ef789", "gh012", "ij345"}
+	if !reflect.DeepEqual(campusIds, expected) {
+		t.Errorf("expected %v, got %v", expected, campusIds)
+	}
+}
+
+func TestReadCampusIdsFromMissingFile(t *testing.T) {
+	_, err := ReadCampusIdsFromFile(filepath.Join(t.TempDir(), "missing.txt"))
+	if !errors.Is(err, utils.ErrNotFound) || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected an error of class %s that wraps fs.ErrNotExist, got %v", utils.ErrNotFound.Name, err)
+	}
+	if utils.ExitCode(err) != utils.ErrNotFound.ExitCode {
+		t.Errorf("expected exit code %d, got %d", utils.ErrNotFound.ExitCode, utils.ExitCode(err))
+	}
+}
+
+func TestFilterIndividualRepositoriesFileKeepsTheEntries(t *testing.T) {
+	srcDir, destDir := t.TempDir(), t.TempDir()
+	srcFilePath := filepath.Join(srcDir, "individual_repositories.json")
+	content := `[
+  {
+    "id": "b1",
+    "members": ["st1"],
+    "individualSelectionCollection": {"zeta": "1", "alpha": "2"}
+  },
+  {
+    "members": ["st2"],
+    "id": "a2"
+  }
+]`
+	if err := os.WriteFile(srcFilePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	count, err := NewMemberFilter([]string{"ST1"}, nil).FilterIndividualRepositoriesFile(srcFilePath, destDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 kept entry, got %d", count)
+	}
+	filteredContent, err := os.ReadFile(filepath.Join(destDir, "individual_repositories.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `[
+  {
+    "id": "b1",
+    "members": [
+      "st1"
+    ],
+    "individualSelectionCollection": {
+      "zeta": "1",
+      "alpha": "2"
+    }
+  }
+]`
+	if string(filteredContent) != expected {
+		t.Errorf("expected:\n%s\nactual:\n%s", expected, filteredContent)
+	}
+}
@@ -12,12 +12,15 @@ import (
 	"github.com/apex/log"
 	"io/ioutil"
 	"os"
+	"strings"
 )
 
 // struct for the repositoryConfig.json file
 type RepositoryConfigFileType struct {
 	FilePath string
-	Content  struct {
+	// the file as it was read, so that keys the struct doesn't know and the order of the keys are kept
+	originalContent []byte
+	Content         struct {
 		General struct {
 			LocalMode                     bool   `json:"localMode"`
 			CreateTestRepository          bool   `json:"createTestRepository"`
@@ -55,34 +58,51 @@ type RepositoryConfigFileType struct {
 	}
 }
 
-// This method is similar to a constructor in OOP
-func NewRepositoryConfigFile(path string) *RepositoryConfigFileType {
+// This method is similar to a constructor in OOP. Returns an error of class utils.ErrNotFound if the file
+// doesn't exist.
+func NewRepositoryConfigFile(path string) (*RepositoryConfigFileType, error) {
 	log.Debug("ars.repositoryConfigFile() - path: " + path)
-	utils.OutputAndAbortIfErrors(utils.ValidateAllFilePaths(path))
+	if err := utils.ValidateFilePath(path); err != nil {
+		return nil, err
+	}
 	return &RepositoryConfigFileType{
 		FilePath: path,
-	}
+	}, nil
 }
 
-func (repositoryConfigFile *RepositoryConfigFileType) ReadContent() error {
-	log.Debug("ars.ReadContent() - filePath: " + repositoryConfigFile.FilePath)
+// Reads the file. The safety rules are checked separately, see the policy package.
+func (repositoryConfigFile *RepositoryConfigFileType) ParseContent() error {
+	log.Debug("ars.ParseContent() - filePath: " + repositoryConfigFile.FilePath)
 	configFile, err := os.ReadFile(repositoryConfigFile.FilePath)
 	if err != nil {
-		return fmt.Errorf("failed to read config file: %v", err)
+		return utils.NewReadError(err, "failed to read config file: %v", err)
 	}
 	err = json.Unmarshal(configFile, &repositoryConfigFile.Content)
 	if err != nil {
-		return fmt.Errorf("failed to unmarshal JSON: %v", err)
+		return utils.NewError(utils.ErrInvalidConfig, "failed to unmarshal JSON in %s: %v",
+			repositoryConfigFile.FilePath, err)
 	}
-	repositoryConfigFile.CheckForDeathTraps()
+	repositoryConfigFile.originalContent = configFile
 	return nil
 }
 
+// Returns the content as JSON, including the keys of the file as it was read that the struct doesn't know,
+// in their original order
+func (repositoryConfigFile *RepositoryConfigFileType) MarshalContent() ([]byte, error) {
+	log.Debug("ars.MarshalContent() - filePath: " + repositoryConfigFile.FilePath)
+	content, err := utils.MarshalJSONPreservingOriginal(repositoryConfigFile.originalContent,
+		repositoryConfigFile.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON: %v", err)
+	}
+	return content, nil
+}
+
 func (repositoryConfigFile *RepositoryConfigFileType) WriteContent() error {
 	log.Debug("ars.WriteContent() - filePath: " + repositoryConfigFile.FilePath)
-	updatedConfig, err := json.MarshalIndent(repositoryConfigFile.Content, "", "  ")
+	updatedConfig, err := repositoryConfigFile.MarshalContent()
 	if err != nil {
-		return fmt.Errorf("failed to marshal JSON: %v", err)
+		return err
 	}
 
 	err = ioutil.WriteFile(repositoryConfigFile.FilePath, updatedConfig, 0644)
@@ -93,17 +113,6 @@ func (repositoryConfigFile *RepositoryConfigFileType) WriteContent() error {
 	return nil
 }
 
-func (repositoryConfigFile *RepositoryConfigFileType) CheckForDeathTraps() bool {
-	log.Debug("ars.checkForDeathTraps() - filePath: " + repositoryConfigFile.FilePath)
-	if !repositoryConfigFile.Content.General.LocalMode && repositoryConfigFile.Content.Remote.DeleteExistingRepositories {
-		utils.Confirm(
-			"Your repositoryConfig.json sets local mode to false, and sets \"deleteExistingRepositories\" \n" +
-				"to true. This means that you'll delete all repositories in the target group. \n" +
-				"Are you sure you want to do this?")
-	}
-	return true
-}
-
 func (repositoryConfigFile *RepositoryConfigFileType) Clone() *RepositoryConfigFileType {
 	log.Debug("ars.Clone() - filePath: " + repositoryConfigFile.FilePath)
 	return repositoryConfigFile.CloneToDifferentLocation(repositoryConfigFile.FilePath)
@@ -111,8 +120,54 @@ func (repositoryConfigFile *RepositoryConfigFileType) Clone() *RepositoryConfigF
 
 func (repositoryConfigFile *RepositoryConfigFileType) CloneToDifferentLocation(newFilePath string) *RepositoryConfigFileType {
 	log.Debug("ars.CloneToDifferentLocation() - newFilePath: " + newFilePath)
-	newFile := NewRepositoryConfigFile(newFilePath)
+	newFile := &RepositoryConfigFileType{}
 	utils.DeepCopy(repositoryConfigFile, newFile)
 	newFile.FilePath = newFilePath
+	newFile.originalContent = repositoryConfigFile.originalContent
 	return newFile
 }
+
+// Returns a repositoryConfig.json with sensible defaults for a new distribution, not yet written to disk.
+// Remote mode is not switched on, and no existing repositories are deleted.
+func NewRepositoryConfigTemplate(path string) *RepositoryConfigFileType {
+	log.Debug("ars.NewRepositoryConfigTemplate() - path: " + path)
+	repositoryConfigFile := &RepositoryConfigFileType{
+		FilePath: path,
+	}
+	content := &repositoryConfigFile.Content
+	content.General.LocalMode = true
+	content.General.CreateTestRepository = true
+	content.General.VariateRepositories = true
+	content.General.DeleteSolution = true
+	content.General.ActivateVariableValueWarnings = true
+	content.General.MaxConcurrentWorkers = 5
+	content.General.GlobalLogLevel = "info"
+	content.Repository.RepositoryName = "{{uuid}}"
+	content.Repository.RepositoryMembers = [][]string{}
+	content.Local.SubsetPaths = []string{}
+	content.Overview.GenerateOverview = true
+	content.Overview.OverviewFileName = "overview"
+	return repositoryConfigFile
+}
+
+// Parses a member list like "ab123, cd456+ef789" into repository members: repositories are separated
+// by commas (or semicolons, or new lines), and the members of a team repository are joined by "+".
+func ParseRepositoryMembers(memberList string) [][]string {
+	repositoryMembers := [][]string{}
+	repositories := strings.FieldsFunc(memberList, func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n' || r == '\r'
+	})
+	for _, repository := range repositories {
+		members := []string{}
+		for _, member := range strings.Split(repository, "+") {
+			member = strings.TrimSpace(member)
+			if member != "" {
+				members = append(members, member)
+			}
+		}
+		if len(members) > 0 {
+			repositoryMembers = append(repositoryMembers, members)
+		}
+	}
+	return repositoryMembers
+}
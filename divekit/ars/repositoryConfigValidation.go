@@ -0,0 +1,439 @@
+package ars
+
+/**
+ * This file contains the strict validation of a repositoryConfig.json file: syntax errors, unknown keys,
+ * wrong types, and missing required keys are reported with their line and column, as well as combinations
+ * of values that the ARS would choke on.
+ */
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/apex/log"
+	"os"
+	"reflect"
+	"strings"
+)
+
+const (
+	ValidationError   = "error"
+	ValidationWarning = "warning"
+)
+
+// Which mode the ARS will run in, as the CLI overrides localMode when it clones the config into the ARS repo
+type ValidationMode int
+
+const (
+	ValidateAsConfigured ValidationMode = iota // as given by general.localMode
+	ValidateForLocalMode
+	ValidateForRemoteMode
+)
+
+// Keys that must be present in a repositoryConfig.json
+var requiredConfigKeys = []string{
+	"general", "general.localMode",
+	"repository", "repository.repositoryName",
+	"individualRepositoryPersist", "local", "remote", "overview",
+}
+
+type ValidationIssue struct {
+	FilePath string
+	Severity string // ValidationError or ValidationWarning
+	Path     string // e.g. "remote.codeRepositoryTargetGroupId", empty for the file as a whole
+	Line     int    // 1-based, 0 if unknown
+	Column   int    // 1-based, 0 if unknown
+	Message  string
+}
+
+// Formats the issue like a compiler message, e.g. "repositoryConfig.json:12:5: error: remote.x: ..."
+func (issue ValidationIssue) String() string {
+	location := issue.FilePath
+	if issue.Line > 0 {
+		location += fmt.Sprintf(":%d:%d", issue.Line, issue.Column)
+	}
+	message := issue.Message
+	if issue.Path != "" {
+		message = issue.Path + ": " + message
+	}
+	return fmt.Sprintf("%s: %s: %s", location, issue.Severity, message)
+}
+
+// Returns true if any of the issues is an error
+func HasValidationErrors(issues []ValidationIssue) bool {
+	for _, issue := range issues {
+		if issue.Severity == ValidationError {
+			return true
+		}
+	}
+	return false
+}
+
+// Validates the repositoryConfig.json file on disk. The returned error is only set if the file cannot
+// be read at all; all problems with its content are returned as issues.
+func (repositoryConfigFile *RepositoryConfigFileType) Validate(mode ValidationMode) ([]ValidationIssue, error) {
+	log.Debug("ars.Validate() - filePath: " + repositoryConfigFile.FilePath)
+	data, err := os.ReadFile(repositoryConfigFile.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+	return repositoryConfigFile.validateData(data, mode), nil
+}
+
+// Validates the content as WriteContent would write it, without writing it. The positions of the issues
+// refer to the content that would be written.
+func (repositoryConfigFile *RepositoryConfigFileType) ValidateContent(mode ValidationMode) ([]ValidationIssue, error) {
+	log.Debug("ars.ValidateContent() - filePath: " + repositoryConfigFile.FilePath)
+	data, err := repositoryConfigFile.MarshalContent()
+	if err != nil {
+		return nil, err
+	}
+	return repositoryConfigFile.validateData(data, mode), nil
+}
+
+func (repositoryConfigFile *RepositoryConfigFileType) validateData(data []byte, mode ValidationMode) []ValidationIssue {
+	validator := &configValidator{
+		filePath:  repositoryConfigFile.FilePath,
+		data:      data,
+		decoder:   json.NewDecoder(strings.NewReader(string(data))),
+		positions: make(map[string]int64),
+	}
+	validator.decoder.UseNumber()
+	err := validator.walkValue("", reflect.TypeOf(repositoryConfigFile.Content))
+	if err != nil {
+		validator.addSyntaxError(err)
+		return validator.issues
+	}
+
+	if HasValidationErrors(validator.issues) {
+		return validator.issues
+	}
+
+	// the structure is fine, so the content can be checked as a whole
+	content := &RepositoryConfigFileType{FilePath: repositoryConfigFile.FilePath}
+	if err = json.Unmarshal(data, &content.Content); err != nil {
+		validator.addIssue(ValidationError, "", 0, err.Error())
+		return validator.issues
+	}
+	validator.checkCombinations(content, mode)
+	return validator.issues
+}
+
+type configValidator struct {
+	filePath  string
+	data      []byte
+	decoder   *json.Decoder
+	positions map[string]int64 // offset of the value of each path that was found
+	issues    []ValidationIssue
+}
+
+// Reads the next value from the decoder, and checks it against the expected type
+func (validator *configValidator) walkValue(path string, expected reflect.Type) error {
+	start := validator.skipSeparators(validator.decoder.InputOffset())
+	validator.positions[path] = start
+	token, err := validator.decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	switch token := token.(type) {
+	case json.Delim:
+		if token == '{' && expected.Kind() == reflect.Struct {
+			return validator.walkObject(path, expected, start)
+		}
+		if token == '[' && expected.Kind() == reflect.Slice {
+			for index := 0; validator.decoder.More(); index++ {
+				if err = validator.walkValue(fmt.Sprintf("%s[%d]", path, index), expected.Elem()); err != nil {
+					return err
+				}
+			}
+			_, err = validator.decoder.Token()
+			return err
+		}
+		validator.addTypeMismatch(path, start, expected, map[json.Delim]string{'{': "an object", '[': "an array"}[token])
+		return validator.skipNested()
+	case bool:
+		if expected.Kind() != reflect.Bool {
+			validator.addTypeMismatch(path, start, expected, "a boolean")
+		}
+	case json.Number:
+		if expected.Kind() != reflect.Int {
+			validator.addTypeMismatch(path, start, expected, "a number")
+		} else if _, err := token.Int64(); err != nil {
+			validator.addTypeMismatch(path, start, expected, "the number "+token.String())
+		}
+	case string:
+		if expected.Kind() != reflect.String {
+			validator.addTypeMismatch(path, start, expected, fmt.Sprintf("the string \"%s\"", token))
+		}
+	case nil:
+		if expected.Kind() != reflect.Slice {
+			validator.addTypeMismatch(path, start, expected, "null")
+		}
+	}
+	return nil
+}
+
+// Walks through the keys of an object, after its opening brace has been read
+func (validator *configValidator) walkObject(path string, expected reflect.Type, start int64) error {
+	seen := make(map[string]bool)
+	for validator.decoder.More() {
+		keyStart := validator.skipSeparators(validator.decoder.InputOffset())
+		token, err := validator.decoder.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+		keyPath := joinConfigPath(path, key)
+		if seen[key] {
+			validator.addIssue(ValidationError, keyPath, keyStart, "duplicate key")
+		}
+		seen[key] = true
+		field, found := fieldByJSONName(expected, key)
+		if !found {
+			validator.addIssue(ValidationWarning, keyPath, keyStart, "unknown key (typo?)")
+			if _, err = validator.decoder.Token(); err != nil {
+				return err
+			}
+			if err = validator.skipNested(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err = validator.walkValue(keyPath, field.Type); err != nil {
+			return err
+		}
+	}
+	if _, err := validator.decoder.Token(); err != nil {
+		return err
+	}
+
+	for _, requiredKey := range requiredConfigKeys {
+		parentPath, key := splitConfigPath(requiredKey)
+		if parentPath == path && !seen[key] {
+			validator.addIssue(ValidationError, requiredKey, start, "required key is missing")
+		}
+	}
+	return nil
+}
+
+// Skips the rest of an object or array whose opening delimiter has just been read. Does nothing if the
+// last token was a plain value.
+func (validator *configValidator) skipNested() error {
+	lastByte := validator.data[validator.decoder.InputOffset()-1]
+	if lastByte != '{' && lastByte != '[' {
+		return nil
+	}
+	for depth := 1; depth > 0; {
+		token, err := validator.decoder.Token()
+		if err != nil {
+			return err
+		}
+		if delim, ok := token.(json.Delim); ok {
+			if delim == '{' || delim == '[' {
+				depth++
+			} else {
+				depth--
+			}
+		}
+	}
+	return nil
+}
+
+// Checks the combinations of values that the ARS can't handle, or that are probably mistakes
+func (validator *configValidator) checkCombinations(repositoryConfigFile *RepositoryConfigFileType, mode ValidationMode) {
+	content := &repositoryConfigFile.Content
+	localMode := content.General.LocalMode
+	if mode != ValidateAsConfigured {
+		localMode = mode == ValidateForLocalMode
+	}
+
+	repository := &content.Repository
+	memberCount := len(repository.RepositoryMembers)
+	if memberCount > 0 && repository.RepositoryCount != 0 && repository.RepositoryCount != memberCount {
+		validator.addIssueAt(ValidationError, "repository.repositoryCount", fmt.Sprintf(
+			"is %d, but there are %d repositoryMembers", repository.RepositoryCount, memberCount))
+	}
+	if memberCount == 0 && repository.RepositoryCount == 0 {
+		validator.addIssueAt(ValidationWarning, "repository.repositoryMembers",
+			"no repositoryMembers and a repositoryCount of 0 - no repos will be created")
+	}
+	memberIndex := make(map[string]int)
+	for index, members := range repository.RepositoryMembers {
+		if len(members) == 0 {
+			validator.addIssueAt(ValidationError, fmt.Sprintf("repository.repositoryMembers[%d]", index),
+				"empty member list")
+		}
+		for position, member := range members {
+			memberPath := fmt.Sprintf("repository.repositoryMembers[%d][%d]", index, position)
+			if strings.TrimSpace(member) == "" {
+				validator.addIssueAt(ValidationError, memberPath, "empty campus ID")
+				continue
+			}
+			if otherIndex, exists := memberIndex[strings.ToLower(member)]; exists {
+				validator.addIssueAt(ValidationWarning, memberPath, fmt.Sprintf(
+					"%s is also a member of repositoryMembers[%d]", member, otherIndex))
+			}
+			memberIndex[strings.ToLower(member)] = index
+		}
+	}
+
+	persist := &content.IndividualRepositoryPersist
+	if persist.UseSavedIndividualRepositories && persist.SavedIndividualRepositoriesFileName == "" {
+		validator.addIssueAt(ValidationError, "individualRepositoryPersist.savedIndividualRepositoriesFileName",
+			"must be set if useSavedIndividualRepositories is true")
+	}
+	if content.General.MaxConcurrentWorkers < 0 {
+		validator.addIssueAt(ValidationError, "general.maxConcurrentWorkers", "must not be negative")
+	}
+
+	if localMode {
+		return
+	}
+	remote := &content.Remote
+	if remote.OriginRepositoryId == 0 {
+		validator.addIssueAt(ValidationError, "remote.originRepositoryId", "must not be 0 in remote mode")
+	}
+	if remote.CodeRepositoryTargetGroupId == 0 {
+		validator.addIssueAt(ValidationError, "remote.codeRepositoryTargetGroupId", "must not be 0 in remote mode")
+	}
+	if content.General.CreateTestRepository && remote.TestRepositoryTargetGroupId == 0 {
+		validator.addIssueAt(ValidationError, "remote.testRepositoryTargetGroupId",
+			"must not be 0 in remote mode if createTestRepository is true")
+	}
+	if content.Overview.GenerateOverview && content.Overview.OverviewRepositoryId == 0 {
+		validator.addIssueAt(ValidationError, "overview.overviewRepositoryId",
+			"must not be 0 in remote mode if generateOverview is true")
+	}
+}
+
+func (validator *configValidator) addTypeMismatch(path string, offset int64, expected reflect.Type, actual string) {
+	validator.addIssue(ValidationError, path, offset,
+		fmt.Sprintf("expected %s, found %s", describeType(expected), actual))
+}
+
+func (validator *configValidator) addSyntaxError(err error) {
+	var syntaxError *json.SyntaxError
+	if errors.As(err, &syntaxError) {
+		// the offset is the one after the character that caused the error
+		validator.addIssue(ValidationError, "", syntaxError.Offset-1, "invalid JSON: "+syntaxError.Error())
+		return
+	}
+	validator.addIssue(ValidationError, "", validator.decoder.InputOffset(), "invalid JSON: "+err.Error())
+}
+
+// Adds an issue at the position of the given path, or of its closest parent that was found in the file
+func (validator *configValidator) addIssueAt(severity, path, message string) {
+	for lookupPath := path; ; {
+		if offset, found := validator.positions[lookupPath]; found {
+			validator.addIssue(severity, path, offset, message)
+			return
+		}
+		if lookupPath == "" {
+			validator.addIssue(severity, path, -1, message)
+			return
+		}
+		lookupPath, _ = splitConfigPath(lookupPath)
+	}
+}
+
+func (validator *configValidator) addIssue(severity, path string, offset int64, message string) {
+	issue := ValidationIssue{
+		FilePath: validator.filePath,
+		Severity: severity,
+		Path:     path,
+		Message:  message,
+	}
+	if offset >= 0 {
+		issue.Line, issue.Column = lineAndColumn(validator.data, offset)
+	}
+	validator.issues = append(validator.issues, issue)
+}
+
+// Returns the offset of the next token, skipping whitespace and the separators between tokens
+func (validator *configValidator) skipSeparators(offset int64) int64 {
+	for offset < int64(len(validator.data)) && strings.IndexByte(" \t\r\n,:", validator.data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+func lineAndColumn(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	line, column := 1, 1
+	for _, char := range string(data[:offset]) {
+		if char == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return line, column
+}
+
+func fieldByJSONName(structType reflect.Type, name string) (reflect.StructField, bool) {
+	for index := 0; index < structType.NumField(); index++ {
+		field := structType.Field(index)
+		if strings.Split(field.Tag.Get("json"), ",")[0] == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func describeType(expected reflect.Type) string {
+	switch expected.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int:
+		return "an integer"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice:
+		return "an array of " + describeTypePlural(expected.Elem())
+	case reflect.Struct:
+		return "an object"
+	default:
+		return expected.String()
+	}
+}
+
+func describeTypePlural(expected reflect.Type) string {
+	switch expected.Kind() {
+	case reflect.Bool:
+		return "booleans"
+	case reflect.Int:
+		return "integers"
+	case reflect.String:
+		return "strings"
+	case reflect.Slice:
+		return "arrays of " + describeTypePlural(expected.Elem())
+	case reflect.Struct:
+		return "objects"
+	default:
+		return expected.String()
+	}
+}
+
+func joinConfigPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Splits "a.b[1]" into "a.b" and "[1]", and "a.b" into "a" and "b"
+func splitConfigPath(path string) (string, string) {
+	if strings.HasSuffix(path, "]") {
+		index := strings.LastIndex(path, "[")
+		return path[:index], path[index:]
+	}
+	index := strings.LastIndex(path, ".")
+	if index < 0 {
+		return "", path
+	}
+	return path[:index], path[index+1:]
+}
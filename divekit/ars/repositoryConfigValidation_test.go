@@ -0,0 +1,227 @@
+package ars
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const validRepositoryConfig = `{
+  "general": {
+    "localMode": false,
+    "createTestRepository": true
+  },
+  "repository": {
+    "repositoryName": "st2-{{now \"2006-01-02\"}}",
+    "repositoryCount": 2,
+    "repositoryMembers": [["ab123"], ["cd456"]]
+  },
+  "individualRepositoryPersist": {},
+  "local": {},
+  "remote": {
+    "originRepositoryId": 1,
+    "codeRepositoryTargetGroupId": 11,
+    "testRepositoryTargetGroupId": 12
+  },
+  "overview": {}
+}
+`
+
+// Writes the config into a temporary file and validates it
+func validateRepositoryConfig(t *testing.T, config string, mode ValidationMode) []ValidationIssue {
+	filePath := filepath.Join(t.TempDir(), "repositoryConfig.json")
+	if err := os.WriteFile(filePath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	issues, err := (&RepositoryConfigFileType{FilePath: filePath}).Validate(mode)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return issues
+}
+
+// Formats the issues like ValidationIssue.String, but without the file path
+func describeIssues(issues []ValidationIssue) []string {
+	descriptions := []string{}
+	for _, issue := range issues {
+		issue.FilePath = "config"
+		descriptions = append(descriptions, issue.String())
+	}
+	return descriptions
+}
+
+func TestValidateRepositoryConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		mode     ValidationMode
+		expected []string
+	}{
+		{
+			name:     "valid config",
+			config:   validRepositoryConfig,
+			mode:     ValidateAsConfigured,
+			expected: []string{},
+		},
+		{
+			name:   "syntax error",
+			config: strings.Replace(validRepositoryConfig, `"localMode": false,`, `"localMode": false`, 1),
+			mode:   ValidateAsConfigured,
+			expected: []string{
+				"config:4:5: error: invalid JSON: invalid character '\"' after object key:value pair",
+			},
+		},
+		{
+			name:     "unknown key",
+			config:   strings.Replace(validRepositoryConfig, `"localMode"`, `"localModus": true, "localMode"`, 1),
+			mode:     ValidateAsConfigured,
+			expected: []string{"config:3:5: warning: general.localModus: unknown key (typo?)"},
+		},
+		{
+			name:   "wrong types",
+			config: strings.Replace(validRepositoryConfig, `"codeRepositoryTargetGroupId": 11`, `"codeRepositoryTargetGroupId": "11"`, 1),
+			mode:   ValidateAsConfigured,
+			expected: []string{
+				"config:15:36: error: remote.codeRepositoryTargetGroupId: expected an integer, found the string \"11\"",
+			},
+		},
+		{
+			name:   "wrong type in an array",
+			config: strings.Replace(validRepositoryConfig, `[["ab123"], ["cd456"]]`, `[["ab123"], "cd456"]`, 1),
+			mode:   ValidateAsConfigured,
+			expected: []string{
+				"config:9:38: error: repository.repositoryMembers[1]: expected an array of strings, found the string \"cd456\"",
+			},
+		},
+		{
+			name:     "duplicate key",
+			config:   strings.Replace(validRepositoryConfig, `"local": {},`, `"local": {},  "local": {},`, 1),
+			mode:     ValidateAsConfigured,
+			expected: []string{"config:12:17: error: local: duplicate key"},
+		},
+		{
+			name:   "missing required keys",
+			config: strings.Replace(validRepositoryConfig, `"repositoryName": "st2-{{now \"2006-01-02\"}}",`, "", 1),
+			mode:   ValidateAsConfigured,
+			expected: []string{
+				"config:6:17: error: repository.repositoryName: required key is missing",
+			},
+		},
+		{
+			name:   "repository count doesn't match the members",
+			config: strings.Replace(validRepositoryConfig, `"repositoryCount": 2`, `"repositoryCount": 3`, 1),
+			mode:   ValidateAsConfigured,
+			expected: []string{
+				"config:8:24: error: repository.repositoryCount: is 3, but there are 2 repositoryMembers",
+			},
+		},
+		{
+			name:   "campus ID in two repos",
+			config: strings.Replace(validRepositoryConfig, `["cd456"]`, `["cd456", "AB123"]`, 1),
+			mode:   ValidateAsConfigured,
+			expected: []string{
+				"config:9:48: warning: repository.repositoryMembers[1][1]: AB123 is also a member of repositoryMembers[0]",
+			},
+		},
+		{
+			name:   "missing group IDs in remote mode",
+			config: strings.Replace(validRepositoryConfig, `"codeRepositoryTargetGroupId": 11,`, "", 1),
+			mode:   ValidateAsConfigured,
+			expected: []string{
+				"config:13:13: error: remote.codeRepositoryTargetGroupId: must not be 0 in remote mode",
+			},
+		},
+		{
+			name:     "missing group IDs in local mode",
+			config:   strings.Replace(validRepositoryConfig, `"codeRepositoryTargetGroupId": 11,`, "", 1),
+			mode:     ValidateForLocalMode,
+			expected: []string{},
+		},
+		{
+			name: "configured local mode overridden for the remote mode",
+			config: strings.Replace(strings.Replace(validRepositoryConfig, `"localMode": false`, `"localMode": true`, 1),
+				`"testRepositoryTargetGroupId": 12`, `"testRepositoryTargetGroupId": 0`, 1),
+			mode: ValidateForRemoteMode,
+			expected: []string{
+				"config:16:36: error: remote.testRepositoryTargetGroupId: must not be 0 in remote mode if " +
+					"createTestRepository is true",
+			},
+		},
+		{
+			name: "configured local mode",
+			config: strings.Replace(strings.Replace(validRepositoryConfig, `"localMode": false`, `"localMode": true`, 1),
+				`"testRepositoryTargetGroupId": 12`, `"testRepositoryTargetGroupId": 0`, 1),
+			mode:     ValidateAsConfigured,
+			expected: []string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := describeIssues(validateRepositoryConfig(t, test.config, test.mode))
+			if strings.Join(actual, "\n") != strings.Join(test.expected, "\n") {
+				t.Errorf("expected:\n%s\nactual:\n%s", strings.Join(test.expected, "\n"), strings.Join(actual, "\n"))
+			}
+		})
+	}
+}
+
+func TestHasValidationErrors(t *testing.T) {
+	warning := ValidationIssue{Severity: ValidationWarning}
+	failure := ValidationIssue{Severity: ValidationError}
+	if HasValidationErrors([]ValidationIssue{warning}) {
+		t.Errorf("expected warnings not to count as errors")
+	}
+	if !HasValidationErrors([]ValidationIssue{warning, failure}) {
+		t.Errorf("expected an error to be found")
+	}
+}
+
+func TestLineAndColumn(t *testing.T) {
+	data := []byte("{\n  \"ä\": 1,\n\t\"b\": 2\n}")
+	tests := []struct {
+		offset         int64
+		expectedLine   int
+		expectedColumn int
+	}{
+		{0, 1, 1},
+		{2, 2, 1},
+		{4, 2, 3},
+		{10, 2, 8}, // after the two bytes of "ä"
+		{13, 3, 1},
+		{14, 3, 2},
+		{int64(len(data)) + 10, 4, 2},
+	}
+	for _, test := range tests {
+		line, column := lineAndColumn(data, test.offset)
+		if line != test.expectedLine || column != test.expectedColumn {
+			t.Errorf("offset %d: expected %d:%d, got %d:%d", test.offset, test.expectedLine, test.expectedColumn,
+				line, column)
+		}
+	}
+}
+
+func TestValidateContent(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "repositoryConfig.json")
+	if err := os.WriteFile(filePath, []byte(validRepositoryConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	repositoryConfigFile := &RepositoryConfigFileType{FilePath: filePath}
+	if err := repositoryConfigFile.ParseContent(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	repositoryConfigFile.Content.Repository.RepositoryCount = 3
+
+	issues, err := repositoryConfigFile.ValidateContent(ValidateAsConfigured)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the positions refer to the content as it would be written, with all keys of the struct
+	expected := "error: repository.repositoryCount: is 3, but there are 2 repositoryMembers"
+	if len(issues) != 1 || !strings.HasSuffix(issues[0].String(), expected) {
+		t.Errorf("expected the issue '%s', got:\n%s", expected, strings.Join(describeIssues(issues), "\n"))
+	}
+	if data, _ := os.ReadFile(filePath); string(data) != validRepositoryConfig {
+		t.Errorf("expected the file to be unchanged")
+	}
+}
@@ -13,30 +13,35 @@ var (
 
 // subcmd.DivekitHomeFlag is the home directory of all the Divekit repos. It is set by the
 // --home flag, the DIVEKIT_HOME environment variable, or the current working directory
-// (in this order).
-func InitDivekitHomeDir(divekitHomeFlag string) {
+// (in this order). Returns an error of class utils.ErrNotFound if the directory doesn't exist.
+func InitDivekitHomeDir(divekitHomeFlag string) error {
 	log.Debug("config.InitDivekitHomeDir()")
 	setDivekitHomeDirFromVariousSources(divekitHomeFlag)
-	utils.OutputAndAbortIfErrors(utils.ValidateAllDirPaths(DivekitHomeDir))
+	if err := utils.JoinErrors(utils.ValidateAllDirPaths(DivekitHomeDir)); err != nil {
+		return err
+	}
 	log.WithFields(log.Fields{
 		"DivekitHomeDir": DivekitHomeDir,
 	}).Info("Setting Divekit Home Dir:")
+	return nil
 }
 
 func setDivekitHomeDirFromVariousSources(divekitHomeFlag string) {
+	var source string
+	DivekitHomeDir, source = DetermineDivekitHomeDir(divekitHomeFlag)
+	log.Info("Home dir " + source + ": " + DivekitHomeDir)
+}
+
+// Determines the home dir from the flag, the DIVEKIT_HOME environment variable, or the current
+// working directory (in this order), without validating it. Also returns a description of the source.
+func DetermineDivekitHomeDir(divekitHomeFlag string) (string, string) {
 	if divekitHomeFlag != "" {
-		log.Info("Home dir is set via flag -m / --home: " + divekitHomeFlag)
-		DivekitHomeDir = divekitHomeFlag
-		return
+		return divekitHomeFlag, "is set via flag -m / --home"
 	}
 	envHome := os.Getenv("DIVEKIT_HOME")
 	if envHome != "" {
-		log.Info("Home dir is set via DIVEKIT_HOME environment variable: " + envHome)
-		DivekitHomeDir = envHome
-		return
+		return envHome, "is set via DIVEKIT_HOME environment variable"
 	}
 	workingDir, _ := os.Getwd()
-	log.Info("Home dir set to current directory: " + workingDir)
-	DivekitHomeDir = workingDir
-	return
+	return workingDir, "set to current directory"
 }
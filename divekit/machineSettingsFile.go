@@ -0,0 +1,102 @@
+package divekit
+
+/**
+ * This file an "object-oriented lookalike" implementation for the machine-level cli-settings.json file. It holds
+ * the settings of the CLI that depend on the machine rather than on the origin repo: how the tools are run.
+ * The file is optional, and searched for in the .divekit-cli folder of the home dir, then in the user's config
+ * dir. The CLI settings of an origin repo can override it.
+ */
+
+import (
+	"divekit-cli/utils"
+	"encoding/json"
+	"github.com/apex/log"
+	"os"
+	"path/filepath"
+)
+
+const MachineSettingsFileName = "cli-settings.json"
+
+// struct for the machine-level cli-settings.json file
+type MachineSettingsFileType struct {
+	FilePath string
+	Exists   bool
+	Content  struct {
+		Tools utils.ToolSettingsType `json:"tools"`
+	}
+}
+
+// Global vars
+var (
+	MachineSettingsFile *MachineSettingsFileType
+)
+
+// Returns the paths where the machine settings are searched for, in this order: in the .divekit-cli folder of
+// the home dir, then in the divekit folder of the user's config dir (e.g. ~/.config/divekit on Linux)
+func MachineSettingsFilePaths() []string {
+	filePaths := []string{filepath.Join(DivekitHomeDir, ".divekit-cli", MachineSettingsFileName)}
+	if userConfigDir, err := os.UserConfigDir(); err == nil {
+		filePaths = append(filePaths, filepath.Join(userConfigDir, "divekit", MachineSettingsFileName))
+	}
+	return filePaths
+}
+
+// This method is similar to a constructor in OOP. Reads the first of the MachineSettingsFilePaths that exists;
+// if none does, the settings are empty and FilePath is the first path. Returns an error of class
+// utils.ErrInvalidConfig if the file can't be read.
+func NewMachineSettingsFile() (*MachineSettingsFileType, error) {
+	log.Debug("divekit.NewMachineSettingsFile()")
+	filePaths := MachineSettingsFilePaths()
+	machineSettingsFile := &MachineSettingsFileType{FilePath: filePaths[0]}
+	for _, filePath := range filePaths {
+		if utils.ValidateFilePath(filePath) == nil {
+			machineSettingsFile.FilePath = filePath
+			machineSettingsFile.Exists = true
+			if err := machineSettingsFile.ReadContent(); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	log.WithFields(log.Fields{
+		"FilePath": machineSettingsFile.FilePath,
+		"Exists":   machineSettingsFile.Exists,
+	}).Debug("Setting machine settings variables:")
+	return machineSettingsFile, nil
+}
+
+func (machineSettingsFile *MachineSettingsFileType) ReadContent() error {
+	log.Debug("divekit.ReadContent() - filePath: " + machineSettingsFile.FilePath)
+	settingsFile, err := os.ReadFile(machineSettingsFile.FilePath)
+	if err != nil {
+		return utils.NewError(utils.ErrInvalidConfig, "failed to read the machine settings file: %v", err)
+	}
+	if err = json.Unmarshal(settingsFile, &machineSettingsFile.Content); err != nil {
+		return utils.NewError(utils.ErrInvalidConfig, "failed to unmarshal JSON in %s: %v",
+			machineSettingsFile.FilePath, err)
+	}
+	for toolName, toolConfig := range machineSettingsFile.ToolConfigs() {
+		if err = toolConfig.Validate(); err != nil {
+			return utils.NewError(utils.ErrInvalidConfig, "%s: the config of the %s: %v",
+				machineSettingsFile.FilePath, toolName, err)
+		}
+	}
+	return nil
+}
+
+// The configs of the tools by tool name, as used by utils.ToolConfigs
+func (machineSettingsFile *MachineSettingsFileType) ToolConfigs() map[string]utils.ToolConfigType {
+	return machineSettingsFile.Content.Tools.ToolConfigs()
+}
+
+// Sets the global MachineSettingsFile, and the tool configs from it. Needs the home dir.
+func InitMachineSettings() error {
+	log.Debug("divekit.InitMachineSettings()")
+	machineSettingsFile, err := NewMachineSettingsFile()
+	if err != nil {
+		return err
+	}
+	MachineSettingsFile = machineSettingsFile
+	utils.ToolConfigs = machineSettingsFile.ToolConfigs()
+	return nil
+}
@@ -0,0 +1,76 @@
+package divekit
+
+import (
+	"divekit-cli/utils"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Sets the home dir and the user config dir to temporary dirs for the duration of a test
+func useTemporaryDirs(t *testing.T) (string, string) {
+	previousHomeDir := DivekitHomeDir
+	t.Cleanup(func() { DivekitHomeDir = previousHomeDir })
+	DivekitHomeDir = t.TempDir()
+	userConfigDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", userConfigDir)
+	t.Setenv("HOME", t.TempDir())      // macOS
+	t.Setenv("AppData", userConfigDir) // Windows
+	return DivekitHomeDir, userConfigDir
+}
+
+func writeMachineSettings(t *testing.T, filePath, content string) {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewMachineSettingsFile(t *testing.T) {
+	homeDir, userConfigDir := useTemporaryDirs(t)
+	homeFile := filepath.Join(homeDir, ".divekit-cli", MachineSettingsFileName)
+	userConfigFile := filepath.Join(userConfigDir, "divekit", MachineSettingsFileName)
+
+	settings, err := NewMachineSettingsFile()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settings.Exists || settings.FilePath != homeFile {
+		t.Errorf("expected no settings at %s, got %+v", homeFile, settings)
+	}
+
+	writeMachineSettings(t, userConfigFile, `{"tools": {"ars": {"executable": "pnpm"}}}`)
+	settings, err = NewMachineSettingsFile()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !settings.Exists || settings.FilePath != userConfigFile ||
+		settings.ToolConfigs()[utils.ToolARS].Executable != "pnpm" {
+		t.Errorf("expected the settings of the user config dir, got %+v", settings)
+	}
+
+	writeMachineSettings(t, homeFile, `{"tools": {"repoEditor": {"timeout": "1h"}}}`)
+	settings, err = NewMachineSettingsFile()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settings.FilePath != homeFile || settings.ToolConfigs()[utils.ToolARS].Executable != "" ||
+		settings.ToolConfigs()[utils.ToolRepoEditor].Timeout != "1h" {
+		t.Errorf("expected only the settings of the home dir, got %+v", settings)
+	}
+}
+
+func TestNewMachineSettingsFileWithInvalidSettings(t *testing.T) {
+	homeDir, _ := useTemporaryDirs(t)
+	homeFile := filepath.Join(homeDir, ".divekit-cli", MachineSettingsFileName)
+	for _, content := range []string{`{"tools": `, `{"tools": {"ars": {"timeout": "soon"}}}`} {
+		writeMachineSettings(t, homeFile, content)
+		_, err := NewMachineSettingsFile()
+		if !errors.Is(err, utils.ErrInvalidConfig) {
+			t.Errorf("%s: expected an error of class %s, got %v", content, utils.ErrInvalidConfig.Name, err)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package origin
+
+/**
+ * This file an "object-oriented lookalike" implementation for the cli-settings.json file in the
+ * .divekit_norepo folder of the origin repo. It holds the origin-specific settings of the CLI. The file
+ * is optional - if it doesn't exist, defaults are used that fit a standard Maven project.
+ */
+
+import (
+	"divekit-cli/divekit/policy"
+	"divekit-cli/utils"
+	"encoding/json"
+	"fmt"
+	"github.com/apex/log"
+	"os"
+	"path"
+	"strings"
+)
+
+// a folder in the origin repo where patch files given by name are searched for
+type SearchRoot struct {
+	Path      string `json:"path"`
+	Recursive bool   `json:"recursive"`
+}
+
+// struct for the cli-settings.json file
+type CLISettingsFileType struct {
+	FilePath string
+	Exists   bool
+	Content  struct {
+		Patch struct {
+			SearchRoots           []SearchRoot `json:"searchRoots"`
+			ExcludePatterns       []string     `json:"excludePatterns"`
+			CommitMessageTemplate string       `json:"commitMessageTemplate"`
+		} `json:"patch"`
+		Policy policy.SettingsType `json:"policy"`
+		// overrides the tool settings of the machine, see divekit.MachineSettingsFileType
+		Tools utils.ToolSettingsType `json:"tools"`
+	}
+}
+
+// directories that are never searched for patch files, regardless of the settings
+var alwaysExcludedDirs = []string{".git", ".divekit_norepo"}
+
+// This method is similar to a constructor in OOP. Returns an error of class utils.ErrInvalidConfig if the
+// file exists, but can't be read.
+func NewCLISettingsFile(filePath string) (*CLISettingsFileType, error) {
+	log.Debug("origin.NewCLISettingsFile() - filePath: " + filePath)
+	cliSettingsFile := &CLISettingsFileType{
+		FilePath: filePath,
+	}
+	cliSettingsFile.setDefaults()
+	if utils.ValidateFilePath(filePath) == nil {
+		cliSettingsFile.Exists = true
+		if err := cliSettingsFile.ReadContent(); err != nil {
+			return nil, utils.ClassifyError(utils.ErrInvalidConfig, err)
+		}
+	}
+	log.WithFields(log.Fields{
+		"FilePath":        cliSettingsFile.FilePath,
+		"Exists":          cliSettingsFile.Exists,
+		"SearchRoots":     cliSettingsFile.Content.Patch.SearchRoots,
+		"ExcludePatterns": cliSettingsFile.Content.Patch.ExcludePatterns,
+	}).Debug("Setting CLI settings variables:")
+	return cliSettingsFile, nil
+}
+
+func (cliSettingsFile *CLISettingsFileType) setDefaults() {
+	cliSettingsFile.Content.Patch.SearchRoots = []SearchRoot{
+		{Path: ".", Recursive: false},
+		{Path: "src", Recursive: true},
+	}
+	cliSettingsFile.Content.Patch.ExcludePatterns = []string{"target/", "build/", "node_modules/"}
+	cliSettingsFile.Content.Policy = policy.DefaultSettings()
+}
+
+// Reads the settings file. Settings missing in the file keep their default values.
+func (cliSettingsFile *CLISettingsFileType) ReadContent() error {
+	log.Debug("origin.ReadContent() - filePath: " + cliSettingsFile.FilePath)
+	settingsFile, err := os.ReadFile(cliSettingsFile.FilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read CLI settings file: %v", err)
+	}
+	err = json.Unmarshal(settingsFile, &cliSettingsFile.Content)
+	if err != nil {
+		return utils.NewError(utils.ErrInvalidConfig, "failed to unmarshal JSON in %s: %v",
+			cliSettingsFile.FilePath, err)
+	}
+	for toolName, toolConfig := range cliSettingsFile.ToolConfigs() {
+		if err = toolConfig.Validate(); err != nil {
+			return utils.NewError(utils.ErrInvalidConfig, "%s: the config of the %s: %v",
+				cliSettingsFile.FilePath, toolName, err)
+		}
+	}
+	return nil
+}
+
+// The configs of the tools by tool name, as used by utils.ToolConfigs
+func (cliSettingsFile *CLISettingsFileType) ToolConfigs() map[string]utils.ToolConfigType {
+	return cliSettingsFile.Content.Tools.ToolConfigs()
+}
+
+// Checks if a path (relative to the origin repo, slash-separated) is excluded from the patch file search.
+// A pattern with a trailing "/" only matches directories. A pattern without any other "/" is matched
+// against the base name at any depth, otherwise against the whole relative path (with "**" support).
+func (cliSettingsFile *CLISettingsFileType) IsExcluded(relPath string, isDir bool) bool {
+	baseName := path.Base(relPath)
+	if isDir {
+		for _, excludedDir := range alwaysExcludedDirs {
+			if baseName == excludedDir {
+				return true
+			}
+		}
+	}
+	for _, pattern := range cliSettingsFile.Content.Patch.ExcludePatterns {
+		dirOnly := strings.HasSuffix(pattern, "/")
+		pattern = strings.TrimSuffix(pattern, "/")
+		if dirOnly && !isDir {
+			continue
+		}
+		var matched bool
+		if strings.Contains(pattern, "/") {
+			matched, _ = utils.MatchDoublestar(pattern, relPath)
+		} else {
+			matched, _ = path.Match(pattern, baseName)
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
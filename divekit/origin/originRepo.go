@@ -8,8 +8,12 @@ import (
 	"divekit-cli/divekit"
 	"divekit-cli/divekit/ars"
 	"divekit-cli/utils"
+	"fmt"
 	"github.com/apex/log"
+	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 )
 
 // global variable for the origin repository
@@ -17,10 +21,20 @@ var (
 	OriginRepo *OriginRepoType
 )
 
+// the fixed names of folders and files in the origin repository
+const (
+	DistributionsDirName             = ".divekit_norepo/distributions"
+	ARSConfigDirName                 = "ars-config_norepo"
+	CLISettingsFileName              = ".divekit_norepo/cli-settings.json"
+	RepositoryConfigFileName         = "repositoryConfig.json"
+	IndividualRepositoriesFilePrefix = "individual_repositories"
+)
+
 // all the relevant paths in the origin repository (all as full paths)
 type OriginRepoType struct {
 	RepoDir         string
 	DistributionMap map[string]*Distribution
+	CLISettingsFile *CLISettingsFileType
 	ARSConfig       struct {
 		Dir string
 	}
@@ -30,25 +44,48 @@ type Distribution struct {
 	Dir                             string
 	RepositoryConfigFile            *ars.RepositoryConfigFileType
 	IndividualizationConfigFileName string
+	PatchHistoryFile                *PatchHistoryFileType
 }
 
-// This method is similar to a constructor in OOP
-func NewOriginRepo(originRepoName string) *OriginRepoType {
+// This method is similar to a constructor in OOP. Returns an error of class utils.ErrNotFound if a part of
+// the repo is missing, utils.ErrAmbiguous if a distribution has several individualization files, and
+// utils.ErrInvalidConfig if the CLI settings can't be read.
+func NewOriginRepo(originRepoName string) (*OriginRepoType, error) {
 	log.Debug("origin.InitOriginRepoPaths()")
 	originRepo := &OriginRepoType{}
 	originRepo.RepoDir = filepath.Join(divekit.DivekitHomeDir, originRepoName)
-	utils.OutputAndAbortIfErrors(utils.ValidateAllDirPaths(originRepo.RepoDir))
+	if err := utils.ValidateDirPath(originRepo.RepoDir); err != nil {
+		return nil, err
+	}
 
-	originRepo.initDistributions()
-	originRepo.ARSConfig.Dir = filepath.Join(originRepo.RepoDir, "ars-config_norepo")
-	utils.OutputAndAbortIfErrors(utils.ValidateAllDirPaths(originRepo.ARSConfig.Dir))
-	return originRepo
+	if err := originRepo.initDistributions(); err != nil {
+		return nil, err
+	}
+	cliSettingsFile, err := NewCLISettingsFile(filepath.Join(originRepo.RepoDir, CLISettingsFileName))
+	if err != nil {
+		return nil, err
+	}
+	originRepo.CLISettingsFile = cliSettingsFile
+	originRepo.ARSConfig.Dir = filepath.Join(originRepo.RepoDir, ARSConfigDirName)
+	if err = utils.ValidateDirPath(originRepo.ARSConfig.Dir); err != nil {
+		return nil, err
+	}
+	return originRepo, nil
 }
 
-func InitOriginRepo(originRepoNameFlag string) {
-	if originRepoNameFlag != "" {
-		OriginRepo = NewOriginRepo(originRepoNameFlag)
+// Sets the global OriginRepo, if an origin repo is given, and overrides the tool configs of the machine with
+// those in its CLI settings
+func InitOriginRepo(originRepoNameFlag string) error {
+	if originRepoNameFlag == "" {
+		return nil
+	}
+	originRepo, err := NewOriginRepo(originRepoNameFlag)
+	if err != nil {
+		return err
 	}
+	OriginRepo = originRepo
+	utils.ToolConfigs = utils.OverrideToolConfigs(utils.ToolConfigs, originRepo.CLISettingsFile.ToolConfigs())
+	return nil
 }
 
 func (originRepo *OriginRepoType) GetDistribution(distributionName string) *Distribution {
@@ -56,29 +93,138 @@ func (originRepo *OriginRepoType) GetDistribution(distributionName string) *Dist
 	return originRepo.DistributionMap[distributionName]
 }
 
-func (originRepo *OriginRepoType) initDistributions() {
+func (originRepo *OriginRepoType) initDistributions() error {
 	log.Debug("origin.initDistributions()")
-	distributionRootDir := filepath.Join(originRepo.RepoDir, ".divekit_norepo/distributions")
+	distributionRootDir := filepath.Join(originRepo.RepoDir, DistributionsDirName)
 	originRepo.DistributionMap = make(map[string]*Distribution)
 	distributionFolders, err := utils.ListSubfolderNames(distributionRootDir)
-	utils.OutputAndAbortIfError(err)
+	if err != nil {
+		return utils.ClassifyError(utils.ErrNotFound, err)
+	}
 
 	for _, distributionName := range distributionFolders {
-		distributionFolder := filepath.Join(distributionRootDir, distributionName)
-		newDistribution := Distribution{
-			Dir: distributionFolder,
+		if err = originRepo.initDistribution(distributionName); err != nil {
+			return err
 		}
-		originRepo.DistributionMap[distributionName] = &newDistribution
-		originRepo.initIndividualRepositoriesFile(distributionName, distributionFolder)
-		originRepo.initRepositorConfigFile(distributionName, distributionFolder)
 	}
+	return nil
+}
+
+func (originRepo *OriginRepoType) initDistribution(distributionName string) error {
+	log.Debug("origin.initDistribution() - distributionName: " + distributionName)
+	distributionFolder := filepath.Join(originRepo.RepoDir, DistributionsDirName, distributionName)
+	newDistribution := Distribution{
+		Dir:              distributionFolder,
+		PatchHistoryFile: NewPatchHistoryFile(filepath.Join(distributionFolder, patchHistoryFileName)),
+	}
+	originRepo.DistributionMap[distributionName] = &newDistribution
+	if err := originRepo.initIndividualRepositoriesFile(distributionName, distributionFolder); err != nil {
+		return err
+	}
+	return originRepo.initRepositorConfigFile(distributionName, distributionFolder)
+}
+
+// Returns the names of all distributions, sorted alphabetically
+func (originRepo *OriginRepoType) DistributionNames() []string {
+	distributionNames := make([]string, 0, len(originRepo.DistributionMap))
+	for distributionName := range originRepo.DistributionMap {
+		distributionNames = append(distributionNames, distributionName)
+	}
+	sort.Strings(distributionNames)
+	return distributionNames
+}
+
+// Creates a new distribution with the given repositoryConfig.json content
+func (originRepo *OriginRepoType) CreateDistribution(distributionName string,
+	repositoryConfigFile *ars.RepositoryConfigFileType) (*Distribution, error) {
+	log.Debug("origin.CreateDistribution() - distributionName: " + distributionName)
+	err := ValidateDistributionName(distributionName)
+	if err != nil {
+		return nil, err
+	}
+	err = ScaffoldDistribution(originRepo.RepoDir, distributionName, repositoryConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	if err = originRepo.initDistribution(distributionName); err != nil {
+		return nil, err
+	}
+	return originRepo.GetDistribution(distributionName), nil
 }
 
-func (originRepo *OriginRepoType) initIndividualRepositoriesFile(distributionName string, distributionFolder string) {
+// Creates a new distribution as a copy of an existing one. The saved individualization is only copied
+// if withIndividualization is set, the patch history never.
+func (originRepo *OriginRepoType) CopyDistribution(srcName, destName string,
+	withIndividualization bool) (*Distribution, error) {
+	log.Debug("origin.CopyDistribution() - srcName: " + srcName + ", destName: " + destName)
+	srcDistribution := originRepo.GetDistribution(srcName)
+	if srcDistribution == nil {
+		return nil, utils.NewError(utils.ErrNotFound, "distribution %s not found", srcName)
+	}
+	repositoryConfigFile := srcDistribution.RepositoryConfigFile.Clone()
+	err := repositoryConfigFile.ParseContent()
+	if err != nil {
+		return nil, err
+	}
+	destDistribution, err := originRepo.CreateDistribution(destName, repositoryConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	if withIndividualization && srcDistribution.IndividualizationConfigFileName != "" {
+		err = utils.CopyFile(srcDistribution.IndividualizationConfigFileName, destDistribution.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy the individualization file: %v", err)
+		}
+		if err = originRepo.initDistribution(destName); err != nil {
+			return nil, err
+		}
+	}
+	return originRepo.GetDistribution(destName), nil
+}
+
+// Deletes a distribution with all its files
+func (originRepo *OriginRepoType) DeleteDistribution(distributionName string) error {
+	log.Debug("origin.DeleteDistribution() - distributionName: " + distributionName)
+	distribution := originRepo.GetDistribution(distributionName)
+	if distribution == nil {
+		return utils.NewError(utils.ErrNotFound, "distribution %s not found", distributionName)
+	}
+	err := os.RemoveAll(distribution.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %v", distribution.Dir, err)
+	}
+	delete(originRepo.DistributionMap, distributionName)
+	return nil
+}
+
+// Checks that a distribution name can be used as a folder name
+func ValidateDistributionName(distributionName string) error {
+	if distributionName == "" || distributionName == "." || distributionName == ".." ||
+		strings.ContainsAny(distributionName, `/\:*?"<>|`) {
+		return utils.NewError(utils.ErrUsage, "invalid distribution name '%s'", distributionName)
+	}
+	return nil
+}
+
+func (originRepo *OriginRepoType) initIndividualRepositoriesFile(distributionName string,
+	distributionFolder string) error {
 	log.Debug("origin.initIndividualRepositoriesFile()")
-	individualRepositoriesFilePath, err :=
-		utils.FindUniqueFileWithPrefix(distributionFolder, "individual_repositories")
-	utils.OutputAndAbortIfError(err)
+	individualRepositoriesFilePaths, err :=
+		utils.FindFilesWithPrefix(distributionFolder, IndividualRepositoriesFilePrefix)
+	if err != nil {
+		return err
+	}
+	if len(individualRepositoriesFilePaths) > 1 {
+		return utils.NewError(utils.ErrAmbiguous, "Multiple files found with prefix '%s' in directory '%s'",
+			IndividualRepositoriesFilePrefix, distributionFolder)
+	}
+	// A distribution that has never been distributed yet has no individualization file.
+	individualRepositoriesFilePath := ""
+	if len(individualRepositoriesFilePaths) == 1 {
+		individualRepositoriesFilePath = individualRepositoriesFilePaths[0]
+	} else {
+		log.Warn("No individual_repositories file found in distribution " + distributionName)
+	}
 	distribution, ok := originRepo.DistributionMap[distributionName]
 	if !ok {
 		// Create a new Distribution if it doesn't exist
@@ -86,12 +232,17 @@ func (originRepo *OriginRepoType) initIndividualRepositoriesFile(distributionNam
 		originRepo.DistributionMap[distributionName] = distribution
 	}
 	distribution.IndividualizationConfigFileName = individualRepositoriesFilePath
+	return nil
 }
 
-func (originRepo *OriginRepoType) initRepositorConfigFile(distributionName string, distributionFolder string) {
+func (originRepo *OriginRepoType) initRepositorConfigFile(distributionName string, distributionFolder string) error {
 	log.Debug("origin.initRepositorConfigFile()")
 	// filename for NewRepositoryConfigFile is fixed, must be "repositoryConfig.json"
-	repositoryConfigFile := ars.NewRepositoryConfigFile(filepath.Join(distributionFolder, "repositoryConfig.json"))
+	repositoryConfigFile, err :=
+		ars.NewRepositoryConfigFile(filepath.Join(distributionFolder, RepositoryConfigFileName))
+	if err != nil {
+		return err
+	}
 	distribution, ok := originRepo.DistributionMap[distributionName]
 	if !ok {
 		// Create a new Distribution if it doesn't exist
@@ -99,4 +250,5 @@ func (originRepo *OriginRepoType) initRepositorConfigFile(distributionName strin
 		originRepo.DistributionMap[distributionName] = distribution
 	}
 	distribution.RepositoryConfigFile = repositoryConfigFile
+	return nil
 }
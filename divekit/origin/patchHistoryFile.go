@@ -0,0 +1,114 @@
+package origin
+
+/**
+ * This file an "object-oriented lookalike" implementation for the patch history ledger of a distribution.
+ * The ledger is stored as patch_history.jsonl in the distribution folder, with one JSON entry per line,
+ * so that each patch run just appends a line.
+ */
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/apex/log"
+	"os"
+	"time"
+)
+
+const patchHistoryFileName = "patch_history.jsonl"
+
+// a file that has been patched, with the hash of its content in the origin repo at the time of patching
+type PatchedFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// one entry in the patch history
+type PatchHistoryEntry struct {
+	Timestamp    time.Time     `json:"timestamp"`
+	Distribution string        `json:"distribution"`
+	Target       string        `json:"target"`
+	Files        []PatchedFile `json:"files"`
+	CommitMsg    string        `json:"commitMsg"`
+	OriginCommit string        `json:"originCommit"`
+	CLIVersion   string        `json:"cliVersion"`
+	DryRun       bool          `json:"dryRun"`
+	Preview      bool          `json:"preview"`
+	Outcome      string        `json:"outcome"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// possible outcomes of a patch run
+const (
+	PatchOutcomeSuccess = "success"
+	PatchOutcomeFailure = "failure"
+	PatchOutcomeDryRun  = "dry-run"
+	PatchOutcomePreview = "preview"
+	// the run was interrupted (e.g. by Ctrl-C) - if during the Repo Editor, some repos may be patched
+	PatchOutcomeInterrupted = "interrupted"
+)
+
+type PatchHistoryFileType struct {
+	FilePath string
+}
+
+// This method is similar to a constructor in OOP. The file doesn't need to exist yet.
+func NewPatchHistoryFile(path string) *PatchHistoryFileType {
+	log.Debug("origin.NewPatchHistoryFile() - path: " + path)
+	return &PatchHistoryFileType{
+		FilePath: path,
+	}
+}
+
+// Appends an entry to the ledger, creating the file if necessary
+func (patchHistoryFile *PatchHistoryFileType) AppendEntry(entry PatchHistoryEntry) error {
+	log.Debug("origin.AppendEntry() - filePath: " + patchHistoryFile.FilePath)
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal patch history entry: %v", err)
+	}
+	file, err := os.OpenFile(patchHistoryFile.FilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open patch history %s: %v", patchHistoryFile.FilePath, err)
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	if err != nil {
+		return fmt.Errorf("failed to write patch history %s: %v", patchHistoryFile.FilePath, err)
+	}
+	return nil
+}
+
+// Reads all entries of the ledger, oldest first. A missing file means an empty history.
+func (patchHistoryFile *PatchHistoryFileType) ReadEntries() ([]PatchHistoryEntry, error) {
+	log.Debug("origin.ReadEntries() - filePath: " + patchHistoryFile.FilePath)
+	entries := []PatchHistoryEntry{}
+	file, err := os.Open(patchHistoryFile.FilePath)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open patch history %s: %v", patchHistoryFile.FilePath, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry PatchHistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal line %d of %s: %v",
+				lineNumber, patchHistoryFile.FilePath, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read patch history %s: %v", patchHistoryFile.FilePath, err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,71 @@
+package origin
+
+/**
+ * This file contains the scaffolding of the Divekit folders in a new origin repository.
+ */
+
+import (
+	"divekit-cli/divekit/ars"
+	"fmt"
+	"github.com/apex/log"
+	"os"
+	"path/filepath"
+)
+
+// explains the repositoryConfig.json files, as JSON doesn't allow comments
+const distributionsReadme = `# Distributions
+
+Each folder here is a distribution: a list of members for which individualized repos are created.
+It contains a repositoryConfig.json for the ARS and, once the repos have been created, exactly one
+individual_repositories_*.json with the saved individualization.
+
+The most important settings in repositoryConfig.json:
+
+- general.localMode: false creates the repos on GitLab, true only generates them locally.
+  (divekit distribute and divekit patch set this value themselves.)
+- general.createTestRepository: whether a test repo is created for each code repo.
+- repository.repositoryName: name pattern of the repos, e.g. "st2-m1-{{uuid}}".
+- repository.repositoryMembers: one array of campus IDs per repo, e.g. [["ab123"], ["cd456", "ef789"]].
+- repository.repositoryCount: number of repos; equal to the number of member arrays if there are members.
+- remote.originRepositoryId: GitLab project ID of the origin repo.
+- remote.codeRepositoryTargetGroupId / remote.testRepositoryTargetGroupId: GitLab group IDs
+  where the code / test repos are created.
+- remote.deleteExistingRepositories: deletes ALL repos in the target groups before creating new ones.
+  Keep this false unless you really know what you are doing.
+- overview.overviewRepositoryId: GitLab project ID of the repo where the overview is stored.
+`
+
+// Creates the Divekit folders in an origin repo. Existing folders are left as they are.
+func ScaffoldOriginRepo(repoDir string) error {
+	log.Debug("origin.ScaffoldOriginRepo() - repoDir: " + repoDir)
+	for _, dir := range []string{filepath.Join(repoDir, DistributionsDirName), filepath.Join(repoDir, ARSConfigDirName)} {
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %v", dir, err)
+		}
+	}
+	readmePath := filepath.Join(repoDir, DistributionsDirName, "README.md")
+	if _, err := os.Stat(readmePath); os.IsNotExist(err) {
+		err = os.WriteFile(readmePath, []byte(distributionsReadme), 0644)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %v", readmePath, err)
+		}
+	}
+	return nil
+}
+
+// Creates a new distribution folder with the given repositoryConfig.json content. Fails if the
+// distribution already exists.
+func ScaffoldDistribution(repoDir, distributionName string, repositoryConfigFile *ars.RepositoryConfigFileType) error {
+	log.Debug("origin.ScaffoldDistribution() - distributionName: " + distributionName)
+	distributionDir := filepath.Join(repoDir, DistributionsDirName, distributionName)
+	if _, err := os.Stat(distributionDir); err == nil {
+		return fmt.Errorf("distribution %s already exists in %s", distributionName, distributionDir)
+	}
+	err := os.MkdirAll(distributionDir, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", distributionDir, err)
+	}
+	repositoryConfigFile.FilePath = filepath.Join(distributionDir, RepositoryConfigFileName)
+	return repositoryConfigFile.WriteContent()
+}
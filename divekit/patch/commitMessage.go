@@ -0,0 +1,59 @@
+package patch
+
+/**
+ * This file contains the templating of the commit message that the Repo Editor uses for the patch commits.
+ */
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/apex/log"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Used if neither a commit message nor a template is given
+const DefaultCommitMsgTemplate = "Patch applied on {{.Date}}"
+
+// the values that can be referenced in a commit message template
+type CommitMsgData struct {
+	Distribution string   // name of the patched distribution
+	Files        []string // patched files, relative to the origin repo
+	FileNames    []string // patched files, just the file names
+	Date         string   // current date and time, as "2006-01-02 15:04"
+}
+
+func NewCommitMsgData(distributionName string, patchFiles []string) CommitMsgData {
+	data := CommitMsgData{
+		Distribution: distributionName,
+		Files:        []string{},
+		FileNames:    []string{},
+		Date:         time.Now().Format("2006-01-02 15:04"),
+	}
+	for _, patchFile := range patchFiles {
+		data.Files = append(data.Files, filepath.ToSlash(patchFile))
+		data.FileNames = append(data.FileNames, filepath.Base(patchFile))
+	}
+	return data
+}
+
+// Renders a commit message template (Go text/template syntax), e.g.
+// "Fix {{join .FileNames \", \"}} in {{.Distribution}}"
+func RenderCommitMsg(commitMsgTemplate string, data CommitMsgData) (string, error) {
+	log.Debug("patch.RenderCommitMsg() - template: " + commitMsgTemplate)
+	parsedTemplate, err := template.New("commitMsg").
+		Funcs(template.FuncMap{"join": strings.Join}).
+		Option("missingkey=error").
+		Parse(commitMsgTemplate)
+	if err != nil {
+		return "", fmt.Errorf("invalid commit message template: %v", err)
+	}
+	var commitMsg bytes.Buffer
+	err = parsedTemplate.Execute(&commitMsg, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to render commit message template: %v", err)
+	}
+	return strings.TrimSpace(commitMsg.String()), nil
+}
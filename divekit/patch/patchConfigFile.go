@@ -19,7 +19,9 @@ import (
 // struct for the editorConfig.json file
 type PatchConfigFileType struct {
 	FilePath string
-	Content  struct {
+	// the file as it was read, so that keys the struct doesn't know and the order of the keys are kept
+	originalContent []byte
+	Content         struct {
 		OnlyUpdateTestProjects bool   `json:"onlyUpdateTestProjects"`
 		OnlyUpdateCodeProjects bool   `json:"onlyUpdateCodeProjects"`
 		GroupIds               []int  `json:"groupIds"`
@@ -28,29 +30,80 @@ type PatchConfigFileType struct {
 	}
 }
 
-// This method is similar to a constructor in OOP
-func NewPatchConfigFile(path string) *PatchConfigFileType {
+// This method is similar to a constructor in OOP. Returns an error of class utils.ErrNotFound if the file
+// doesn't exist.
+func NewPatchConfigFile(path string) (*PatchConfigFileType, error) {
 	log.Debug("patch.patchConfigFile() - path: " + path)
-	utils.OutputAndAbortIfErrors(utils.ValidateAllFilePaths(path))
+	if err := utils.ValidateFilePath(path); err != nil {
+		return nil, err
+	}
 	log.WithFields(log.Fields{
 		"PatchConfigFileType.FilePath": path,
 	}).Info("Setting NewPatchConfigFile variables:")
 	return &PatchConfigFileType{
 		FilePath: path,
+	}, nil
+}
+
+// which repos of a distribution are patched
+const (
+	PatchTargetCode = "code"
+	PatchTargetTest = "test"
+	PatchTargetBoth = "both"
+)
+
+// Checks that the repos to be patched actually exist according to the repositoryConfig.json of the distribution.
+// The target "both" means the code repos, plus the test repos if the distribution has them. Returns an error of
+// class utils.ErrUsage for an invalid target, and utils.ErrInvalidConfig if the repos don't exist.
+func ValidatePatchTarget(target string, repositoryConfigFile *ars.RepositoryConfigFileType) error {
+	log.Debug("patch.ValidatePatchTarget() - target: " + target)
+	content := repositoryConfigFile.Content
+	if target != PatchTargetCode && target != PatchTargetTest && target != PatchTargetBoth {
+		return utils.NewError(utils.ErrUsage, "invalid patch target %s (must be code, test, or both)", target)
+	}
+	if target != PatchTargetTest && content.Remote.CodeRepositoryTargetGroupId == 0 {
+		return utils.NewError(utils.ErrInvalidConfig, "patch target %s needs a codeRepositoryTargetGroupId in %s",
+			target, repositoryConfigFile.FilePath)
+	}
+	if target == PatchTargetTest && !content.General.CreateTestRepository {
+		return utils.NewError(utils.ErrInvalidConfig, "patch target %s needs test repositories, but createTestRepository is false in %s",
+			target, repositoryConfigFile.FilePath)
 	}
+	if patchesTestRepos(target, repositoryConfigFile) && content.Remote.TestRepositoryTargetGroupId == 0 {
+		return utils.NewError(utils.ErrInvalidConfig, "patch target %s needs a testRepositoryTargetGroupId in %s",
+			target, repositoryConfigFile.FilePath)
+	}
+	return nil
+}
+
+// Whether the test repos are patched: always for the target "test", and for "both" only if the distribution
+// has test repos
+func patchesTestRepos(target string, repositoryConfigFile *ars.RepositoryConfigFileType) bool {
+	return target == PatchTargetTest ||
+		(target == PatchTargetBoth && repositoryConfigFile.Content.General.CreateTestRepository)
 }
 
-func (patchConfigFile *PatchConfigFileType) UpdateFromRepositoryConfigFile(repositoryConfigFile *ars.RepositoryConfigFileType) error {
+func (patchConfigFile *PatchConfigFileType) UpdateFromRepositoryConfigFile(
+	repositoryConfigFile *ars.RepositoryConfigFileType, target string, commitMsg string) error {
 	log.Debug("patch.UpdateFromRepositoryConfigFile() - repositoryConfigFile: " + repositoryConfigFile.FilePath)
-	patchConfigFile.Content.OnlyUpdateTestProjects = false
-	patchConfigFile.Content.OnlyUpdateCodeProjects = false
-	patchConfigFile.Content.GroupIds = make([]int, 2)
-	patchConfigFile.Content.GroupIds[0] = repositoryConfigFile.Content.Remote.CodeRepositoryTargetGroupId
-	patchConfigFile.Content.GroupIds[1] = repositoryConfigFile.Content.Remote.TestRepositoryTargetGroupId
+	patchCodeRepos := target != PatchTargetTest
+	patchTestRepos := patchesTestRepos(target, repositoryConfigFile)
+	patchConfigFile.Content.OnlyUpdateTestProjects = !patchCodeRepos
+	patchConfigFile.Content.OnlyUpdateCodeProjects = !patchTestRepos
+	patchConfigFile.Content.GroupIds = []int{}
+	if patchCodeRepos {
+		patchConfigFile.Content.GroupIds = append(patchConfigFile.Content.GroupIds,
+			repositoryConfigFile.Content.Remote.CodeRepositoryTargetGroupId)
+	}
+	if patchTestRepos {
+		patchConfigFile.Content.GroupIds = append(patchConfigFile.Content.GroupIds,
+			repositoryConfigFile.Content.Remote.TestRepositoryTargetGroupId)
+	}
 	patchConfigFile.Content.LogLevel = utils.LogLevelAsString()
-	currentTime := time.Now()
-	formattedTime := currentTime.Format("2006-01-02 15:04")
-	patchConfigFile.Content.CommitMsg = "Patch applied on " + formattedTime
+	if commitMsg == "" {
+		commitMsg = "Patch applied on " + time.Now().Format("2006-01-02 15:04")
+	}
+	patchConfigFile.Content.CommitMsg = commitMsg
 	err := patchConfigFile.WriteContent()
 	return err
 }
@@ -59,18 +112,22 @@ func (patchConfigFile *PatchConfigFileType) ReadContent() error {
 	log.Debug("patch.ReadContent() - filePath: " + patchConfigFile.FilePath)
 	configFile, err := os.ReadFile(patchConfigFile.FilePath)
 	if err != nil {
-		return fmt.Errorf("failed to read config file: %v", err)
+		return utils.NewReadError(err, "failed to read config file: %v", err)
 	}
 	err = json.Unmarshal(configFile, &patchConfigFile.Content)
 	if err != nil {
-		return fmt.Errorf("failed to unmarshal JSON: %v", err)
+		return utils.NewError(utils.ErrInvalidConfig, "failed to unmarshal JSON in %s: %v",
+			patchConfigFile.FilePath, err)
 	}
+	patchConfigFile.originalContent = configFile
 	return nil
 }
 
+// Writes the content, keeping the keys of the file as it was read that the struct doesn't know,
+// in their original order
 func (patchConfigFile *PatchConfigFileType) WriteContent() error {
 	log.Debug("patch.WriteContent() - filePath: " + patchConfigFile.FilePath)
-	updatedConfig, err := json.MarshalIndent(patchConfigFile.Content, "", "  ")
+	updatedConfig, err := utils.MarshalJSONPreservingOriginal(patchConfigFile.originalContent, patchConfigFile.Content)
 	if err != nil {
 		return fmt.Errorf("failed to marshal JSON: %v", err)
 	}
@@ -8,13 +8,15 @@ import (
 	"divekit-cli/divekit"
 	"divekit-cli/divekit/ars"
 	"divekit-cli/utils"
-	"fmt"
 	"github.com/apex/log"
 	"os"
 	"path/filepath"
 )
 
-// all the paths used in the ARS repository (all as full paths)
+// name of the Repo Editor repo, which must be cloned under this name into the Divekit home dir
+const RepoName = "divekit-repo-editor"
+
+// all the paths used in the Repo Editor repository (all as full paths)
 type PatchRepoType struct {
 	RepoDir         string
 	PatchConfigFile *PatchConfigFileType
@@ -22,44 +24,77 @@ type PatchRepoType struct {
 }
 
 // This method is similar to a constructor in OOP
-func NewPatchRepo() *PatchRepoType {
+func NewPatchRepo() (*PatchRepoType, error) {
 	log.Debug("patch.NewPatchRepo()")
-	patchRepo := &PatchRepoType{}
-	patchRepo.RepoDir = filepath.Join(divekit.DivekitHomeDir, "divekit-repo-editor")
-	patchConfigFileName := filepath.Join(patchRepo.RepoDir, "src/main/config/editorConfig.json")
-	patchRepo.PatchConfigFile = NewPatchConfigFile(patchConfigFileName)
-	patchRepo.InputDir = filepath.Join(patchRepo.RepoDir, "assets/input")
+	return NewPatchRepoInDir(filepath.Join(divekit.DivekitHomeDir, RepoName))
+}
 
-	utils.OutputAndAbortIfErrors(utils.ValidateAllDirPaths(patchRepo.RepoDir, patchRepo.InputDir))
+// Same as NewPatchRepo, but for a copy of the Repo Editor repo in a different directory (e.g. a run workspace)
+// Returns an error of class utils.ErrNotFound if a part of the repo is missing.
+func NewPatchRepoInDir(repoDir string) (*PatchRepoType, error) {
+	log.Debug("patch.NewPatchRepoInDir() - repoDir: " + repoDir)
+	patchRepo := newPatchRepoLayoutInDir(repoDir)
+	if err := utils.JoinErrors(patchRepo.Validate()); err != nil {
+		return nil, err
+	}
 	log.WithFields(log.Fields{
 		"patchRepo.RepoDir":   patchRepo.RepoDir,
 		" patchRepo.InputDir": patchRepo.InputDir,
 	}).Info("Setting patch repo variables:")
+	return patchRepo, nil
+}
+
+// Returns the expected layout of the Repo Editor repo, without checking if it actually exists
+func NewPatchRepoLayout() *PatchRepoType {
+	log.Debug("patch.NewPatchRepoLayout()")
+	return newPatchRepoLayoutInDir(filepath.Join(divekit.DivekitHomeDir, RepoName))
+}
+
+func newPatchRepoLayoutInDir(repoDir string) *PatchRepoType {
+	patchRepo := &PatchRepoType{}
+	patchRepo.RepoDir = repoDir
+	patchRepo.PatchConfigFile = &PatchConfigFileType{
+		FilePath: filepath.Join(patchRepo.RepoDir, "src/main/config/editorConfig.json"),
+	}
+	patchRepo.InputDir = filepath.Join(patchRepo.RepoDir, "assets/input")
 	return patchRepo
 }
 
+// Checks that all directories and files of the Repo Editor repo exist, and returns all problems found
+func (patchRepo *PatchRepoType) Validate() []error {
+	log.Debug("patch.Validate()")
+	errorsList := utils.ValidateAllDirPaths(patchRepo.RepoDir, patchRepo.InputDir)
+	return append(errorsList, utils.ValidateAllFilePaths(patchRepo.PatchConfigFile.FilePath)...)
+}
+
 func (patchRepo *PatchRepoType) CleanInputDir() error {
 	codeDirPath := filepath.Join(patchRepo.InputDir, "code")
 	testDirPath := filepath.Join(patchRepo.InputDir, "test")
 	errCode := os.RemoveAll(codeDirPath)
 	errTest := os.RemoveAll(testDirPath)
 	if errCode != nil {
-		fmt.Println("Error removing code input directory %s:", errCode)
+		log.Errorf("Error removing code input directory: %v", errCode)
 		return errCode
 	}
 	if errTest != nil {
-		fmt.Println("Error removing test input directory %s:", errTest)
+		log.Errorf("Error removing test input directory: %v", errTest)
 		return errTest
 	}
 	return nil
 }
 
-func (patchRepo *PatchRepoType) UpdatePatchConfigFile(repositoryConfigFile *ars.RepositoryConfigFileType) error {
+func (patchRepo *PatchRepoType) UpdatePatchConfigFile(
+	repositoryConfigFile *ars.RepositoryConfigFileType, target string, commitMsg string) error {
 	log.Debug("patch.UpdatePatchConfigFile()")
 	patchConfigFile := patchRepo.PatchConfigFile
-	err := patchConfigFile.UpdateFromRepositoryConfigFile(repositoryConfigFile)
+	// read the existing file first, so that the settings the CLI doesn't touch are kept
+	if err := patchConfigFile.ReadContent(); err != nil {
+		log.Errorf("Error in patch.UpdatePatchConfigFile(): %v", err)
+		return err
+	}
+	err := patchConfigFile.UpdateFromRepositoryConfigFile(repositoryConfigFile, target, commitMsg)
 	if err != nil {
-		log.Errorf("Error in patch.UpdatePatchConfigFile():", err)
+		log.Errorf("Error in patch.UpdatePatchConfigFile(): %v", err)
 		return err
 	}
 	err = patchConfigFile.WriteContent()
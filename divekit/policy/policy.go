@@ -0,0 +1,154 @@
+package policy
+
+/**
+ * This file contains the policy engine, which evaluates safety rules ("death traps") before the ARS or the
+ * Repo Editor is run. Each rule has a severity: a violation is only logged (warn), has to be confirmed by the
+ * user (confirm), or aborts the run (block). The severity can be changed per origin repo in cli-settings.json,
+ * and a single run can accept the risk of a rule explicitly.
+ */
+
+import (
+	"divekit-cli/divekit/ars"
+	"divekit-cli/utils"
+	"github.com/apex/log"
+	"sort"
+)
+
+const (
+	SeverityOff     = "off"
+	SeverityWarn    = "warn"
+	SeverityConfirm = "confirm"
+	SeverityBlock   = "block"
+)
+
+// Everything the rules need to know about a run
+type RunContextType struct {
+	Command          string // "patch" or "distribute"
+	DistributionName string
+	// the config as the ARS will see it, i.e. with the values set by the CLI
+	RepositoryConfig *ars.RepositoryConfigFileType
+	// the configs of the other distributions of the origin repo, by distribution name
+	OtherDistributions map[string]*ars.RepositoryConfigFileType
+	PatchFiles         []string // only for patch, relative to the origin repo
+	PatchTarget        string   // only for patch
+	Settings           SettingsType
+}
+
+// The policy settings in the cli-settings.json of the origin repo
+type SettingsType struct {
+	Severities           map[string]string `json:"severities"`           // rule name => severity
+	StaffDistributions   []string          `json:"staffDistributions"`   // distributions for the staff, not students
+	StaffIds             []string          `json:"staffIds"`             // campus IDs of the staff
+	SolutionOnlyPatterns []string          `json:"solutionOnlyPatterns"` // files that only belong in the solution
+}
+
+type RuleType struct {
+	Name        string
+	Severity    string // the default severity, if the settings don't give one
+	Description string
+	// returns a message for each violation of the rule, none if the run is fine
+	Check func(run *RunContextType) []string
+}
+
+type ViolationType struct {
+	Rule     *RuleType
+	Severity string // the effective severity
+	Message  string
+	Accepted bool // the risk was accepted explicitly for this run
+}
+
+var rules = []*RuleType{}
+
+// Returns the default policy settings
+func DefaultSettings() SettingsType {
+	return SettingsType{
+		Severities:           map[string]string{},
+		StaffDistributions:   []string{"test"},
+		StaffIds:             []string{},
+		SolutionOnlyPatterns: []string{"**/*_solution*", "**/*_solution*/**"},
+	}
+}
+
+// Adds a rule to the rules that are evaluated before each run
+func RegisterRule(rule *RuleType) {
+	rules = append(rules, rule)
+}
+
+// Returns all registered rules, sorted by name
+func Rules() []*RuleType {
+	sorted := append([]*RuleType{}, rules...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+func FindRule(name string) *RuleType {
+	for _, rule := range rules {
+		if rule.Name == name {
+			return rule
+		}
+	}
+	return nil
+}
+
+// Returns the severity of the rule, as changed by the settings
+func (rule *RuleType) EffectiveSeverity(settings SettingsType) string {
+	if severity, exists := settings.Severities[rule.Name]; exists {
+		return severity
+	}
+	return rule.Severity
+}
+
+// Checks that the settings only refer to existing rules and valid severities, and that the accepted
+// risks are names of rules. Returns an error of class utils.ErrInvalidConfig for invalid settings, and
+// utils.ErrUsage for an unknown accepted risk.
+func ValidateSettings(settings SettingsType, acceptedRisks []string) error {
+	for name, severity := range settings.Severities {
+		if FindRule(name) == nil {
+			return utils.NewError(utils.ErrInvalidConfig, "unknown rule '%s' in the policy severities", name)
+		}
+		if severity != SeverityOff && severity != SeverityWarn && severity != SeverityConfirm &&
+			severity != SeverityBlock {
+			return utils.NewError(utils.ErrInvalidConfig,
+				"invalid severity '%s' for rule %s (must be off, warn, confirm, or block)",
+				severity, name)
+		}
+	}
+	for _, name := range acceptedRisks {
+		if FindRule(name) == nil {
+			return utils.NewError(utils.ErrUsage, "unknown rule '%s' in --accept-risk", name)
+		}
+	}
+	return nil
+}
+
+// Evaluates all rules that are not switched off for a run
+func Evaluate(run *RunContextType, acceptedRisks []string) []ViolationType {
+	log.Debug("policy.Evaluate() - command: " + run.Command + ", distribution: " + run.DistributionName)
+	violations := []ViolationType{}
+	for _, rule := range Rules() {
+		severity := rule.EffectiveSeverity(run.Settings)
+		if severity == SeverityOff {
+			continue
+		}
+		for _, message := range rule.Check(run) {
+			violations = append(violations, ViolationType{
+				Rule:     rule,
+				Severity: severity,
+				Message:  message,
+				Accepted: containsString(acceptedRisks, rule.Name),
+			})
+		}
+	}
+	return violations
+}
+
+func containsString(list []string, value string) bool {
+	for _, element := range list {
+		if element == value {
+			return true
+		}
+	}
+	return false
+}
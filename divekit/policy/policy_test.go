@@ -0,0 +1,74 @@
+package policy
+
+import (
+	"divekit-cli/utils"
+	"errors"
+	"testing"
+)
+
+func TestEvaluateWithSeverityAndAcceptedRisk(t *testing.T) {
+	tests := []struct {
+		name             string
+		severity         string // the override in the settings, "" for the default
+		acceptedRisks    []string
+		expectedCount    int
+		expectedSeverity string
+		expectedAccepted bool
+	}{
+		{"default severity", "", nil, 1, SeverityBlock, false},
+		{"overridden severity", SeverityWarn, nil, 1, SeverityWarn, false},
+		{"switched off", SeverityOff, nil, 0, "", false},
+		{"accepted", "", []string{"identical-target-groups"}, 1, SeverityBlock, true},
+		{"overridden and accepted", SeverityConfirm, []string{"identical-target-groups"}, 1, SeverityConfirm, true},
+		{"switched off and accepted", SeverityOff, []string{"identical-target-groups"}, 0, "", false},
+		{"other risk accepted", "", []string{"overview-in-target-group"}, 1, SeverityBlock, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			run := newTestRun(newTestConfig(10, 10))
+			if test.severity != "" {
+				run.Settings.Severities["identical-target-groups"] = test.severity
+			}
+			violations := Evaluate(run, test.acceptedRisks)
+			if len(violations) != test.expectedCount {
+				t.Fatalf("expected %d violation(s), got %d: %v", test.expectedCount, len(violations), violations)
+			}
+			if test.expectedCount == 0 {
+				return
+			}
+			violation := violations[0]
+			if violation.Rule.Name != "identical-target-groups" {
+				t.Errorf("expected a violation of identical-target-groups, got %s", violation.Rule.Name)
+			}
+			if violation.Severity != test.expectedSeverity {
+				t.Errorf("expected severity %s, got %s", test.expectedSeverity, violation.Severity)
+			}
+			if violation.Accepted != test.expectedAccepted {
+				t.Errorf("expected accepted to be %v, got %v", test.expectedAccepted, violation.Accepted)
+			}
+		})
+	}
+}
+
+func TestValidateSettings(t *testing.T) {
+	settings := DefaultSettings()
+	settings.Severities["identical-target-groups"] = SeverityWarn
+	if err := ValidateSettings(settings, []string{"delete-existing-repositories"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	settings.Severities["identical-target-groups"] = "ignore"
+	if err := ValidateSettings(settings, nil); !errors.Is(err, utils.ErrInvalidConfig) {
+		t.Errorf("expected an error of class %s for an invalid severity, got %v", utils.ErrInvalidConfig.Name, err)
+	}
+
+	settings = DefaultSettings()
+	settings.Severities["no-such-rule"] = SeverityWarn
+	if err := ValidateSettings(settings, nil); !errors.Is(err, utils.ErrInvalidConfig) {
+		t.Errorf("expected an error of class %s for an unknown rule, got %v", utils.ErrInvalidConfig.Name, err)
+	}
+
+	if err := ValidateSettings(DefaultSettings(), []string{"no-such-rule"}); !errors.Is(err, utils.ErrUsage) {
+		t.Errorf("expected an error of class %s for an unknown accepted risk, got %v", utils.ErrUsage.Name, err)
+	}
+}
@@ -0,0 +1,181 @@
+package policy
+
+/**
+ * This file contains the built-in safety rules.
+ */
+
+import (
+	"divekit-cli/divekit/ars"
+	"divekit-cli/divekit/patch"
+	"divekit-cli/utils"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func init() {
+	RegisterRule(&RuleType{
+		Name:        "delete-existing-repositories",
+		Severity:    SeverityConfirm,
+		Description: "remote mode with deleteExistingRepositories deletes all repos in the target groups",
+		Check:       checkDeleteExistingRepositories,
+	})
+	RegisterRule(&RuleType{
+		Name:        "staff-distribution-targets-student-group",
+		Severity:    SeverityBlock,
+		Description: "a staff distribution (e.g. test) uses a target group of a student distribution",
+		Check:       checkStaffDistributionTargetsStudentGroup,
+	})
+	RegisterRule(&RuleType{
+		Name:        "staff-in-student-distribution",
+		Severity:    SeverityConfirm,
+		Description: "the members of a student distribution contain staff IDs",
+		Check:       checkStaffInStudentDistribution,
+	})
+	RegisterRule(&RuleType{
+		Name:        "identical-target-groups",
+		Severity:    SeverityBlock,
+		Description: "the code and the test repos have the same target group",
+		Check:       checkIdenticalTargetGroups,
+	})
+	RegisterRule(&RuleType{
+		Name:        "overview-in-target-group",
+		Severity:    SeverityConfirm,
+		Description: "the overview repo ID is the same as a target group ID",
+		Check:       checkOverviewInTargetGroup,
+	})
+	RegisterRule(&RuleType{
+		Name:        "patch-solution-only-file",
+		Severity:    SeverityBlock,
+		Description: "a patch file matches a solution-only pattern, but the code repos are patched",
+		Check:       checkPatchSolutionOnlyFile,
+	})
+}
+
+func checkDeleteExistingRepositories(run *RunContextType) []string {
+	content := &run.RepositoryConfig.Content
+	if content.General.LocalMode || !content.Remote.DeleteExistingRepositories {
+		return nil
+	}
+	return []string{fmt.Sprintf("deleteExistingRepositories is true: ALL repos in group %d (code) and %d (test) "+
+		"will be deleted", content.Remote.CodeRepositoryTargetGroupId, content.Remote.TestRepositoryTargetGroupId)}
+}
+
+func checkStaffDistributionTargetsStudentGroup(run *RunContextType) []string {
+	if !containsString(run.Settings.StaffDistributions, run.DistributionName) {
+		return nil
+	}
+	messages := []string{}
+	for _, groupId := range targetGroupIds(run.RepositoryConfig) {
+		for _, distributionName := range sortedDistributionNames(run.OtherDistributions) {
+			if containsString(run.Settings.StaffDistributions, distributionName) {
+				continue
+			}
+			for _, otherGroupId := range targetGroupIds(run.OtherDistributions[distributionName]) {
+				if groupId == otherGroupId {
+					messages = append(messages, fmt.Sprintf(
+						"staff distribution %s targets group %d, which is a target group of student distribution %s",
+						run.DistributionName, groupId, distributionName))
+				}
+			}
+		}
+	}
+	return messages
+}
+
+func checkStaffInStudentDistribution(run *RunContextType) []string {
+	if containsString(run.Settings.StaffDistributions, run.DistributionName) {
+		return nil
+	}
+	// the members of the staff distributions count as staff, too
+	staffIds := make(map[string]string)
+	for _, staffId := range run.Settings.StaffIds {
+		staffIds[strings.ToLower(staffId)] = "cli-settings.json"
+	}
+	for _, distributionName := range sortedDistributionNames(run.OtherDistributions) {
+		if !containsString(run.Settings.StaffDistributions, distributionName) {
+			continue
+		}
+		for _, members := range run.OtherDistributions[distributionName].Content.Repository.RepositoryMembers {
+			for _, member := range members {
+				staffIds[strings.ToLower(member)] = "distribution " + distributionName
+			}
+		}
+	}
+	messages := []string{}
+	for _, members := range run.RepositoryConfig.Content.Repository.RepositoryMembers {
+		for _, member := range members {
+			if source, isStaff := staffIds[strings.ToLower(member)]; isStaff {
+				messages = append(messages, fmt.Sprintf("%s is a member of student distribution %s, but is staff "+
+					"according to %s", member, run.DistributionName, source))
+			}
+		}
+	}
+	return messages
+}
+
+func checkIdenticalTargetGroups(run *RunContextType) []string {
+	content := &run.RepositoryConfig.Content
+	if !content.General.CreateTestRepository || content.Remote.CodeRepositoryTargetGroupId == 0 ||
+		content.Remote.CodeRepositoryTargetGroupId != content.Remote.TestRepositoryTargetGroupId {
+		return nil
+	}
+	return []string{fmt.Sprintf("code and test repos both target group %d",
+		content.Remote.CodeRepositoryTargetGroupId)}
+}
+
+func checkOverviewInTargetGroup(run *RunContextType) []string {
+	overview := &run.RepositoryConfig.Content.Overview
+	if !overview.GenerateOverview || overview.OverviewRepositoryId == 0 {
+		return nil
+	}
+	for _, groupId := range targetGroupIds(run.RepositoryConfig) {
+		if groupId == overview.OverviewRepositoryId {
+			return []string{fmt.Sprintf("the overview repo ID %d is also a target group ID - is it really the "+
+				"ID of the overview repo?", groupId)}
+		}
+	}
+	return nil
+}
+
+func checkPatchSolutionOnlyFile(run *RunContextType) []string {
+	if run.Command != "patch" || run.PatchTarget == patch.PatchTargetTest {
+		return nil
+	}
+	messages := []string{}
+	for _, patchFile := range run.PatchFiles {
+		for _, pattern := range run.Settings.SolutionOnlyPatterns {
+			// the patterns use slashes, the patch files the separator of the OS
+			if matched, _ := utils.MatchDoublestar(pattern, filepath.ToSlash(patchFile)); matched {
+				messages = append(messages, fmt.Sprintf("%s matches the solution-only pattern %s, and would be "+
+					"patched into the students' code repos", patchFile, pattern))
+				break
+			}
+		}
+	}
+	return messages
+}
+
+// Returns the non-zero target group IDs of a config
+func targetGroupIds(repositoryConfigFile *ars.RepositoryConfigFileType) []int {
+	groupIds := []int{}
+	remote := &repositoryConfigFile.Content.Remote
+	if remote.CodeRepositoryTargetGroupId != 0 {
+		groupIds = append(groupIds, remote.CodeRepositoryTargetGroupId)
+	}
+	if repositoryConfigFile.Content.General.CreateTestRepository && remote.TestRepositoryTargetGroupId != 0 &&
+		remote.TestRepositoryTargetGroupId != remote.CodeRepositoryTargetGroupId {
+		groupIds = append(groupIds, remote.TestRepositoryTargetGroupId)
+	}
+	return groupIds
+}
+
+func sortedDistributionNames(distributions map[string]*ars.RepositoryConfigFileType) []string {
+	names := []string{}
+	for name := range distributions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,147 @@
+package policy
+
+import (
+	"divekit-cli/divekit/ars"
+	"divekit-cli/divekit/patch"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Returns a remote config with the given target groups and members, and with a test repo
+func newTestConfig(codeGroupId, testGroupId int, members ...[]string) *ars.RepositoryConfigFileType {
+	repositoryConfigFile := &ars.RepositoryConfigFileType{FilePath: "repositoryConfig.json"}
+	content := &repositoryConfigFile.Content
+	content.General.CreateTestRepository = true
+	content.Remote.CodeRepositoryTargetGroupId = codeGroupId
+	content.Remote.TestRepositoryTargetGroupId = testGroupId
+	content.Repository.RepositoryMembers = members
+	return repositoryConfigFile
+}
+
+// Returns a distribute run of the "students" distribution with the default settings
+func newTestRun(repositoryConfigFile *ars.RepositoryConfigFileType) *RunContextType {
+	return &RunContextType{
+		Command:            "distribute",
+		DistributionName:   "students",
+		RepositoryConfig:   repositoryConfigFile,
+		OtherDistributions: map[string]*ars.RepositoryConfigFileType{},
+		Settings:           DefaultSettings(),
+	}
+}
+
+// Checks that the rule finds as many violations as expected, and that the first message contains expectedText
+func checkRule(t *testing.T, ruleName string, run *RunContextType, expectedCount int, expectedText string) {
+	t.Helper()
+	rule := FindRule(ruleName)
+	if rule == nil {
+		t.Fatalf("rule %s is not registered", ruleName)
+	}
+	messages := rule.Check(run)
+	if len(messages) != expectedCount {
+		t.Fatalf("expected %d violation(s) of %s, got %d: %v", expectedCount, ruleName, len(messages), messages)
+	}
+	if expectedCount > 0 && !strings.Contains(messages[0], expectedText) {
+		t.Errorf("expected the message to contain '%s', got '%s'", expectedText, messages[0])
+	}
+}
+
+func TestDeleteExistingRepositories(t *testing.T) {
+	run := newTestRun(newTestConfig(10, 20))
+	checkRule(t, "delete-existing-repositories", run, 0, "")
+
+	run.RepositoryConfig.Content.Remote.DeleteExistingRepositories = true
+	checkRule(t, "delete-existing-repositories", run, 1, "group 10 (code) and 20 (test)")
+
+	run.RepositoryConfig.Content.General.LocalMode = true
+	checkRule(t, "delete-existing-repositories", run, 0, "")
+}
+
+func TestStaffDistributionTargetsStudentGroup(t *testing.T) {
+	run := newTestRun(newTestConfig(10, 20))
+	run.DistributionName = "test"
+	run.OtherDistributions["students"] = newTestConfig(30, 40)
+	run.OtherDistributions["other-test"] = newTestConfig(10, 20)
+	run.Settings.StaffDistributions = []string{"test", "other-test"}
+	checkRule(t, "staff-distribution-targets-student-group", run, 0, "")
+
+	run.OtherDistributions["students"] = newTestConfig(30, 10)
+	checkRule(t, "staff-distribution-targets-student-group", run, 1,
+		"staff distribution test targets group 10, which is a target group of student distribution students")
+
+	// only staff distributions are checked
+	run.DistributionName = "students2"
+	checkRule(t, "staff-distribution-targets-student-group", run, 0, "")
+}
+
+func TestStaffInStudentDistribution(t *testing.T) {
+	run := newTestRun(newTestConfig(10, 20, []string{"st1"}, []string{"st2", "Prof1"}))
+	checkRule(t, "staff-in-student-distribution", run, 0, "")
+
+	run.Settings.StaffIds = []string{"prof1"}
+	checkRule(t, "staff-in-student-distribution", run, 1, "Prof1 is a member of student distribution students, "+
+		"but is staff according to cli-settings.json")
+
+	// the members of a staff distribution count as staff
+	run.Settings.StaffIds = []string{}
+	run.OtherDistributions["test"] = newTestConfig(30, 40, []string{"st2"})
+	checkRule(t, "staff-in-student-distribution", run, 1, "st2 is a member of student distribution students, "+
+		"but is staff according to distribution test")
+
+	// a staff distribution itself isn't checked
+	run.DistributionName = "test"
+	checkRule(t, "staff-in-student-distribution", run, 0, "")
+}
+
+func TestIdenticalTargetGroups(t *testing.T) {
+	run := newTestRun(newTestConfig(10, 20))
+	checkRule(t, "identical-target-groups", run, 0, "")
+
+	run.RepositoryConfig.Content.Remote.TestRepositoryTargetGroupId = 10
+	checkRule(t, "identical-target-groups", run, 1, "code and test repos both target group 10")
+
+	run.RepositoryConfig.Content.General.CreateTestRepository = false
+	checkRule(t, "identical-target-groups", run, 0, "")
+
+	run = newTestRun(newTestConfig(0, 0))
+	checkRule(t, "identical-target-groups", run, 0, "")
+}
+
+func TestOverviewInTargetGroup(t *testing.T) {
+	run := newTestRun(newTestConfig(10, 20))
+	overview := &run.RepositoryConfig.Content.Overview
+	overview.OverviewRepositoryId = 20
+	checkRule(t, "overview-in-target-group", run, 0, "")
+
+	overview.GenerateOverview = true
+	checkRule(t, "overview-in-target-group", run, 1, "the overview repo ID 20 is also a target group ID")
+
+	overview.OverviewRepositoryId = 30
+	checkRule(t, "overview-in-target-group", run, 0, "")
+}
+
+func TestPatchSolutionOnlyFile(t *testing.T) {
+	run := newTestRun(newTestConfig(10, 20))
+	run.PatchFiles = []string{
+		filepath.Join("src", "main", "java", "Shop.java"),
+		filepath.Join("src", "main", "java", "Shop_solution.java"),
+		filepath.Join("src", "shop_solution", "Cart.java"),
+	}
+	checkRule(t, "patch-solution-only-file", run, 0, "")
+
+	run.Command = "patch"
+	run.PatchTarget = patch.PatchTargetCode
+	checkRule(t, "patch-solution-only-file", run, 2,
+		filepath.Join("src", "main", "java", "Shop_solution.java")+" matches the solution-only pattern **/*_solution*")
+
+	run.PatchTarget = patch.PatchTargetBoth
+	checkRule(t, "patch-solution-only-file", run, 2, "")
+
+	run.PatchTarget = patch.PatchTargetTest
+	checkRule(t, "patch-solution-only-file", run, 0, "")
+
+	run.PatchTarget = patch.PatchTargetCode
+	run.Settings.SolutionOnlyPatterns = []string{"src/solution/"}
+	run.PatchFiles = []string{filepath.Join("src", "solution", "Shop.java"), filepath.Join("src", "Shop.java")}
+	checkRule(t, "patch-solution-only-file", run, 1, "matches the solution-only pattern src/solution/")
+}
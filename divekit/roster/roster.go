@@ -0,0 +1,211 @@
+package roster
+
+/**
+ * This file contains the import and export of student rosters as CSV files, e.g. the exports of campus
+ * lists. A roster is turned into the repositoryMembers of a repositoryConfig.json: one repo per student,
+ * or one repo per team if there is a team column.
+ */
+
+import (
+	"divekit-cli/utils"
+	"encoding/csv"
+	"fmt"
+	"github.com/apex/log"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Column names that are recognized as campus ID / team column, if no column is given explicitly
+var (
+	campusIdColumnNames = []string{"campusid", "campus id", "campus-id", "campus_id", "kennung", "username", "id"}
+	teamColumnNames     = []string{"team", "group", "gruppe", "teamnumber", "team number", "groupnumber"}
+)
+
+// Used to check the campus IDs, unless a different pattern is given
+const DefaultCampusIdPattern = `^[a-zA-Z][a-zA-Z0-9._-]*$`
+
+type ImportOptions struct {
+	CampusIdColumn  string // header name or 1-based column number, empty for auto-detection
+	TeamColumn      string // header name or 1-based column number, empty for auto-detection, "-" for none
+	Delimiter       rune   // 0 for auto-detection (comma or semicolon)
+	NoHeader        bool
+	CampusIdPattern string
+}
+
+type ImportResult struct {
+	RepositoryMembers [][]string
+	Duplicates        []string // campus IDs that occur more than once (only the first occurrence is used)
+	Malformed         []string // "line n: value" of rows with an invalid or missing campus ID
+}
+
+// Reads a roster CSV file and builds the repository members from it
+func ImportRoster(filePath string, options ImportOptions) (*ImportResult, error) {
+	log.Debug("roster.ImportRoster() - filePath: " + filePath)
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, utils.NewReadError(err, "failed to read roster: %v", err)
+	}
+	text := strings.TrimPrefix(string(content), "\ufeff")
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = options.Delimiter
+	if reader.Comma == 0 {
+		reader.Comma = detectDelimiter(text)
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, utils.NewError(utils.ErrInvalidConfig, "failed to parse roster %s: %v", filePath, err)
+	}
+	if len(records) == 0 {
+		return nil, utils.NewError(utils.ErrInvalidConfig, "roster %s is empty", filePath)
+	}
+
+	var header []string
+	firstDataLine := 0
+	if !options.NoHeader {
+		header = records[0]
+		firstDataLine = 1
+	}
+	campusIdIndex, err := findColumn(header, options.CampusIdColumn, campusIdColumnNames, true)
+	if err != nil {
+		return nil, err
+	}
+	teamIndex := -1
+	if options.TeamColumn != "-" {
+		teamIndex, err = findColumn(header, options.TeamColumn, teamColumnNames, options.TeamColumn != "")
+		if err != nil {
+			return nil, err
+		}
+	}
+	campusIdPattern := options.CampusIdPattern
+	if campusIdPattern == "" {
+		campusIdPattern = DefaultCampusIdPattern
+	}
+	campusIdRegexp, err := regexp.Compile(campusIdPattern)
+	if err != nil {
+		return nil, utils.NewError(utils.ErrUsage, "invalid campus ID pattern: %v", err)
+	}
+
+	result := &ImportResult{RepositoryMembers: [][]string{}, Duplicates: []string{}, Malformed: []string{}}
+	seen := make(map[string]bool)
+	teamIndexes := make(map[string]int)
+	for lineIndex := firstDataLine; lineIndex < len(records); lineIndex++ {
+		record := records[lineIndex]
+		if isEmptyRecord(record) {
+			continue
+		}
+		campusId := normalizeCampusId(fieldAt(record, campusIdIndex))
+		if !campusIdRegexp.MatchString(campusId) {
+			result.Malformed = append(result.Malformed, fmt.Sprintf("line %d: '%s'", lineIndex+1, campusId))
+			continue
+		}
+		if seen[campusId] {
+			result.Duplicates = append(result.Duplicates, campusId)
+			continue
+		}
+		seen[campusId] = true
+
+		team := strings.TrimSpace(fieldAt(record, teamIndex))
+		if teamIndex < 0 || team == "" {
+			result.RepositoryMembers = append(result.RepositoryMembers, []string{campusId})
+			continue
+		}
+		repoIndex, exists := teamIndexes[team]
+		if !exists {
+			repoIndex = len(result.RepositoryMembers)
+			teamIndexes[team] = repoIndex
+			result.RepositoryMembers = append(result.RepositoryMembers, []string{})
+		}
+		result.RepositoryMembers[repoIndex] = append(result.RepositoryMembers[repoIndex], campusId)
+	}
+	return result, nil
+}
+
+// Writes the repository members as CSV with the columns campusId and team (the 1-based repo number)
+func ExportRoster(writer io.Writer, repositoryMembers [][]string) error {
+	log.Debug("roster.ExportRoster()")
+	csvWriter := csv.NewWriter(writer)
+	err := csvWriter.Write([]string{"campusId", "team"})
+	for repoIndex, members := range repositoryMembers {
+		for _, member := range members {
+			if err == nil {
+				err = csvWriter.Write([]string{member, strconv.Itoa(repoIndex + 1)})
+			}
+		}
+	}
+	csvWriter.Flush()
+	if err == nil {
+		err = csvWriter.Error()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write roster: %v", err)
+	}
+	return nil
+}
+
+func detectDelimiter(text string) rune {
+	firstLine := strings.SplitN(text, "\n", 2)[0]
+	if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		return ';'
+	}
+	return ','
+}
+
+// Finds a column by its header name or 1-based number. If column is empty, one of the known names is
+// looked for in the header. Returns -1 if no column is found and it is not required.
+func findColumn(header []string, column string, knownNames []string, required bool) (int, error) {
+	if column != "" {
+		if number, err := strconv.Atoi(column); err == nil {
+			if number < 1 {
+				return -1, utils.NewError(utils.ErrUsage, "invalid column number %d", number)
+			}
+			return number - 1, nil
+		}
+		for index, name := range header {
+			if strings.EqualFold(strings.TrimSpace(name), column) {
+				return index, nil
+			}
+		}
+		return -1, utils.NewError(utils.ErrNotFound, "column '%s' not found in the roster header %v", column, header)
+	}
+	for index, name := range header {
+		for _, knownName := range knownNames {
+			if strings.EqualFold(strings.TrimSpace(name), knownName) {
+				return index, nil
+			}
+		}
+	}
+	if header == nil && required {
+		// without a header, the campus ID is expected in the first column
+		return 0, nil
+	}
+	if required {
+		return -1, utils.NewError(utils.ErrNotFound, "no campus ID column found in the roster header %v - please specify it", header)
+	}
+	return -1, nil
+}
+
+// Campus IDs are compared case-insensitively, as in the member filter of the ars package
+func normalizeCampusId(campusId string) string {
+	return strings.ToLower(strings.TrimSpace(campusId))
+}
+
+func fieldAt(record []string, index int) string {
+	if index < 0 || index >= len(record) {
+		return ""
+	}
+	return record[index]
+}
+
+func isEmptyRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
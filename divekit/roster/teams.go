@@ -0,0 +1,254 @@
+package roster
+
+/**
+ * This file contains the formation of teams from a list of campus IDs, for distributions with group repos.
+ * The same campus IDs, options and seed always give the same teams, so that an assignment can be re-derived.
+ */
+
+import (
+	"divekit-cli/utils"
+	"github.com/apex/log"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+)
+
+// How the students are ordered before they are split into teams
+const (
+	StrategyRandom       = "random"
+	StrategyAlphabetical = "alphabetical"
+	StrategyPreferences  = "preferences"
+)
+
+// What happens to the students that don't fill a complete team
+const (
+	RemainderBalance    = "balance"    // teams as even as possible, at most size
+	RemainderSmaller    = "smaller"    // one last, smaller team
+	RemainderDistribute = "distribute" // spread over the other teams, which get size+1 members
+	RemainderFail       = "fail"
+)
+
+var (
+	Strategies        = []string{StrategyRandom, StrategyAlphabetical, StrategyPreferences}
+	RemainderPolicies = []string{RemainderBalance, RemainderSmaller, RemainderDistribute, RemainderFail}
+)
+
+type TeamOptions struct {
+	Size            int
+	Strategy        string
+	Seed            int64 // used by the random and the preferences strategy
+	PreferencesFile string
+	Remainder       string
+}
+
+// Forms teams of the given campus IDs. The order of the campus IDs doesn't matter.
+func FormTeams(campusIds []string, options TeamOptions) ([][]string, error) {
+	log.Debug("roster.FormTeams()")
+	if options.Size < 1 {
+		return nil, utils.NewError(utils.ErrUsage, "invalid team size %d", options.Size)
+	}
+	if len(campusIds) == 0 {
+		return nil, utils.NewError(utils.ErrInvalidConfig, "there are no students to form teams of")
+	}
+	sortedIds := append([]string{}, campusIds...)
+	sort.Strings(sortedIds)
+	teamSizes, err := computeTeamSizes(len(sortedIds), options.Size, options.Remainder)
+	if err != nil {
+		return nil, err
+	}
+
+	switch options.Strategy {
+	case StrategyAlphabetical:
+		return fillTeams(teamSizes, nil, sortedIds), nil
+	case StrategyRandom:
+		shuffle(sortedIds, options.Seed)
+		return fillTeams(teamSizes, nil, sortedIds), nil
+	case StrategyPreferences:
+		preferences, err := readPreferences(options.PreferencesFile, sortedIds)
+		if err != nil {
+			return nil, err
+		}
+		teams, err := placePreferences(teamSizes, preferences)
+		if err != nil {
+			return nil, err
+		}
+		remainingIds := []string{}
+		for _, campusId := range sortedIds {
+			if !containsId(preferences, campusId) {
+				remainingIds = append(remainingIds, campusId)
+			}
+		}
+		shuffle(remainingIds, options.Seed)
+		return fillTeams(teamSizes, teams, remainingIds), nil
+	default:
+		return nil, utils.NewError(utils.ErrUsage, "invalid strategy '%s', must be one of %v", options.Strategy,
+			Strategies)
+	}
+}
+
+// Computes the sizes of the teams for count students, according to the remainder policy
+func computeTeamSizes(count, size int, remainder string) ([]int, error) {
+	fullTeams := count / size
+	rest := count % size
+	sizes := []int{}
+	switch remainder {
+	case RemainderBalance:
+		teamCount := (count + size - 1) / size
+		for index := 0; index < teamCount; index++ {
+			// the first count % teamCount teams get one member more
+			teamSize := count / teamCount
+			if index < count%teamCount {
+				teamSize++
+			}
+			sizes = append(sizes, teamSize)
+		}
+	case RemainderSmaller:
+		for index := 0; index < fullTeams; index++ {
+			sizes = append(sizes, size)
+		}
+		if rest > 0 {
+			sizes = append(sizes, rest)
+		}
+	case RemainderDistribute:
+		if rest > 0 && fullTeams == 0 {
+			return nil, utils.NewError(utils.ErrUsage,
+				"cannot distribute %d remaining students, there is no complete team of %d", rest, size)
+		}
+		for index := 0; index < fullTeams; index++ {
+			sizes = append(sizes, size)
+		}
+		for index := 0; index < rest; index++ {
+			sizes[index%fullTeams]++
+		}
+	case RemainderFail:
+		if rest > 0 {
+			return nil, utils.NewError(utils.ErrUsage, "%d students cannot be split into teams of %d (%d remaining)",
+				count, size, rest)
+		}
+		for index := 0; index < fullTeams; index++ {
+			sizes = append(sizes, size)
+		}
+	default:
+		return nil, utils.NewError(utils.ErrUsage, "invalid remainder policy '%s', must be one of %v", remainder,
+			RemainderPolicies)
+	}
+	return sizes, nil
+}
+
+// Fills the teams up to their sizes with the given campus IDs, in order. teams may be nil or partially filled.
+func fillTeams(teamSizes []int, teams [][]string, campusIds []string) [][]string {
+	if teams == nil {
+		teams = make([][]string, len(teamSizes))
+	}
+	next := 0
+	for index, teamSize := range teamSizes {
+		if teams[index] == nil {
+			teams[index] = []string{}
+		}
+		for len(teams[index]) < teamSize && next < len(campusIds) {
+			teams[index] = append(teams[index], campusIds[next])
+			next++
+		}
+	}
+	return teams
+}
+
+func shuffle(campusIds []string, seed int64) {
+	random := rand.New(rand.NewSource(seed))
+	random.Shuffle(len(campusIds), func(i, j int) {
+		campusIds[i], campusIds[j] = campusIds[j], campusIds[i]
+	})
+}
+
+// Reads a preference file: each line lists the campus IDs of students who want to be in the same team,
+// separated by commas, semicolons or spaces. Lines starting with # are ignored, as are campus IDs that are not
+// in the roster (with a warning). Campus IDs are matched case-insensitively; the preferences contain them as
+// they are spelled in campusIds.
+func readPreferences(filePath string, campusIds []string) ([][]string, error) {
+	log.Debug("roster.readPreferences() - filePath: " + filePath)
+	if filePath == "" {
+		return nil, utils.NewError(utils.ErrUsage, "the preferences strategy needs a preference file")
+	}
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, utils.NewReadError(err, "failed to read preference file: %v", err)
+	}
+	lines := strings.Split(strings.TrimPrefix(string(content), "\ufeff"), "\n")
+
+	rosterIds := make(map[string]string) // normalized campus ID -> campus ID
+	for _, campusId := range campusIds {
+		rosterIds[normalizeCampusId(campusId)] = campusId
+	}
+	preferenceOf := make(map[string]int)
+	preferences := [][]string{}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		preference := []string{}
+		for _, field := range strings.FieldsFunc(line, isPreferenceSeparator) {
+			normalizedId := normalizeCampusId(field)
+			if normalizedId == "" {
+				continue
+			}
+			campusId, inRoster := rosterIds[normalizedId]
+			if !inRoster {
+				log.Warnf("Ignoring %s in the preference file, it is not in the roster", strings.TrimSpace(field))
+				continue
+			}
+			if number, exists := preferenceOf[campusId]; exists {
+				return nil, utils.NewError(utils.ErrInvalidConfig,
+					"%s is in more than one preference (preferences %d and %d) in %s", campusId, number,
+					len(preferences)+1, filePath)
+			}
+			preferenceOf[campusId] = len(preferences) + 1
+			preference = append(preference, campusId)
+		}
+		if len(preference) > 0 {
+			preferences = append(preferences, preference)
+		}
+	}
+	return preferences, nil
+}
+
+// Places the preferred groups into the teams, the largest groups first, each into the first team with enough
+// room left
+func placePreferences(teamSizes []int, preferences [][]string) ([][]string, error) {
+	sorted := append([][]string{}, preferences...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+	teams := make([][]string, len(teamSizes))
+	for _, preference := range sorted {
+		placed := false
+		for index, teamSize := range teamSizes {
+			if len(teams[index])+len(preference) <= teamSize {
+				teams[index] = append(teams[index], preference...)
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			return nil, utils.NewError(utils.ErrInvalidConfig, "the preferred team %v doesn't fit into any team",
+				preference)
+		}
+	}
+	return teams, nil
+}
+
+func isPreferenceSeparator(char rune) bool {
+	return char == ',' || char == ';' || char == '\t' || char == ' '
+}
+
+func containsId(groups [][]string, campusId string) bool {
+	for _, group := range groups {
+		for _, member := range group {
+			if member == campusId {
+				return true
+			}
+		}
+	}
+	return false
+}
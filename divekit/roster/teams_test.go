@@ -0,0 +1,189 @@
+package roster
+
+import (
+	"divekit-cli/utils"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestComputeTeamSizes(t *testing.T) {
+	tests := []struct {
+		name          string
+		count         int
+		size          int
+		remainder     string
+		expected      []int
+		expectedClass *utils.ErrorClassType
+	}{
+		{"balance, even", 6, 2, RemainderBalance, []int{2, 2, 2}, nil},
+		{"balance, one short", 7, 3, RemainderBalance, []int{3, 2, 2}, nil},
+		{"balance, several short", 10, 4, RemainderBalance, []int{4, 3, 3}, nil},
+		{"balance, fewer students than the size", 2, 3, RemainderBalance, []int{2}, nil},
+		{"smaller, even", 6, 3, RemainderSmaller, []int{3, 3}, nil},
+		{"smaller, with rest", 7, 3, RemainderSmaller, []int{3, 3, 1}, nil},
+		{"distribute, even", 4, 2, RemainderDistribute, []int{2, 2}, nil},
+		{"distribute, with rest", 8, 3, RemainderDistribute, []int{4, 4}, nil},
+		{"distribute, rest larger than the teams", 7, 3, RemainderDistribute, []int{4, 3}, nil},
+		{"distribute, no complete team", 2, 3, RemainderDistribute, nil, utils.ErrUsage},
+		{"fail, even", 6, 3, RemainderFail, []int{3, 3}, nil},
+		{"fail, with rest", 7, 3, RemainderFail, nil, utils.ErrUsage},
+		{"unknown policy", 6, 3, "round-robin", nil, utils.ErrUsage},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sizes, err := computeTeamSizes(test.count, test.size, test.remainder)
+			if test.expectedClass != nil {
+				if !errors.Is(err, test.expectedClass) {
+					t.Fatalf("expected an error of class %s, got %v", test.expectedClass.Name, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(sizes, test.expected) {
+				t.Errorf("expected team sizes %v, got %v", test.expected, sizes)
+			}
+			total := 0
+			for _, size := range sizes {
+				total += size
+			}
+			if total != test.count {
+				t.Errorf("expected %d students in the teams, got %d", test.count, total)
+			}
+		})
+	}
+}
+
+func TestFormTeamsAlphabetical(t *testing.T) {
+	teams, err := FormTeams([]string{"ee555", "aa111", "dd444", "bb222", "cc333"},
+		TeamOptions{Size: 2, Strategy: StrategyAlphabetical, Remainder: RemainderSmaller})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := [][]string{{"aa111", "bb222"}, {"cc333", "dd444"}, {"ee555"}}
+	if !reflect.DeepEqual(teams, expected) {
+		t.Errorf("expected %v, got %v", expected, teams)
+	}
+}
+
+func TestFormTeamsRandomIsReproducible(t *testing.T) {
+	campusIds := []string{"aa111", "bb222", "cc333", "dd444", "ee555", "ff666"}
+	options := TeamOptions{Size: 3, Strategy: StrategyRandom, Seed: 42, Remainder: RemainderBalance}
+	teams, err := FormTeams(campusIds, options)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reversed := []string{"ff666", "ee555", "dd444", "cc333", "bb222", "aa111"}
+	teamsAgain, err := FormTeams(reversed, options)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(teams, teamsAgain) {
+		t.Errorf("expected the same seed to give the same teams, got %v and %v", teams, teamsAgain)
+	}
+	if len(teams) != 2 || len(teams[0]) != 3 || len(teams[1]) != 3 {
+		t.Errorf("expected two teams of 3, got %v", teams)
+	}
+	assertSameStudents(t, campusIds, teams)
+}
+
+func TestFormTeamsWithPreferences(t *testing.T) {
+	// the campus IDs of existing members may be spelled in upper case
+	campusIds := []string{"AA111", "bb222", "cc333", "dd444", "ee555", "ff666"}
+	preferencesFile := writePreferences(t, "# wishes\nbb222, aa111\n\nFF666;cc333;xx999\n")
+	teams, err := FormTeams(campusIds, TeamOptions{Size: 2, Strategy: StrategyPreferences, Seed: 7,
+		PreferencesFile: preferencesFile, Remainder: RemainderBalance})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(teams) != 3 {
+		t.Fatalf("expected 3 teams, got %v", teams)
+	}
+	if !reflect.DeepEqual(teams[0], []string{"bb222", "AA111"}) {
+		t.Errorf("expected the first preference in the first team, got %v", teams[0])
+	}
+	if !reflect.DeepEqual(teams[1], []string{"ff666", "cc333"}) {
+		t.Errorf("expected the second preference in the second team, got %v", teams[1])
+	}
+	assertSameStudents(t, campusIds, teams)
+}
+
+func TestFormTeamsWithInvalidPreferences(t *testing.T) {
+	campusIds := []string{"aa111", "bb222", "cc333", "dd444"}
+	tests := []struct {
+		name          string
+		preferences   string
+		expectedClass *utils.ErrorClassType
+	}{
+		{"student in two preferences", "aa111 bb222\ncc333 AA111\n", utils.ErrInvalidConfig},
+		{"preference larger than a team", "aa111 bb222 cc333\n", utils.ErrInvalidConfig},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := FormTeams(campusIds, TeamOptions{Size: 2, Strategy: StrategyPreferences,
+				PreferencesFile: writePreferences(t, test.preferences), Remainder: RemainderBalance})
+			if !errors.Is(err, test.expectedClass) {
+				t.Errorf("expected an error of class %s, got %v", test.expectedClass.Name, err)
+			}
+		})
+	}
+
+	_, err := FormTeams(campusIds, TeamOptions{Size: 2, Strategy: StrategyPreferences,
+		PreferencesFile: filepath.Join(t.TempDir(), "missing.txt"), Remainder: RemainderBalance})
+	if !errors.Is(err, utils.ErrNotFound) {
+		t.Errorf("expected an error of class %s for a missing preference file, got %v", utils.ErrNotFound.Name, err)
+	}
+}
+
+func TestFormTeamsWithInvalidOptions(t *testing.T) {
+	campusIds := []string{"aa111", "bb222"}
+	tests := []struct {
+		name          string
+		campusIds     []string
+		options       TeamOptions
+		expectedClass *utils.ErrorClassType
+	}{
+		{"team size 0", campusIds, TeamOptions{Size: 0, Strategy: StrategyRandom, Remainder: RemainderBalance},
+			utils.ErrUsage},
+		{"unknown strategy", campusIds, TeamOptions{Size: 2, Strategy: "by-grade", Remainder: RemainderBalance},
+			utils.ErrUsage},
+		{"no students", []string{}, TeamOptions{Size: 2, Strategy: StrategyRandom, Remainder: RemainderBalance},
+			utils.ErrInvalidConfig},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := FormTeams(test.campusIds, test.options)
+			if !errors.Is(err, test.expectedClass) {
+				t.Errorf("expected an error of class %s, got %v", test.expectedClass.Name, err)
+			}
+		})
+	}
+}
+
+func writePreferences(t *testing.T, content string) string {
+	filePath := filepath.Join(t.TempDir(), "preferences.txt")
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filePath
+}
+
+// Checks that every campus ID is in exactly one of the teams
+func assertSameStudents(t *testing.T, campusIds []string, teams [][]string) {
+	t.Helper()
+	students := []string{}
+	for _, team := range teams {
+		students = append(students, team...)
+	}
+	expected := append([]string{}, campusIds...)
+	sort.Strings(students)
+	sort.Strings(expected)
+	if !reflect.DeepEqual(students, expected) {
+		t.Errorf("expected the teams to contain %v, got %v", expected, students)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package runlock
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Checks if a process with the given PID runs on this host
+func processExists(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	// EPERM: the process exists, but belongs to another user
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
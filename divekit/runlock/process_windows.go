@@ -0,0 +1,18 @@
+//go:build windows
+
+package runlock
+
+import (
+	"os"
+)
+
+// Checks if a process with the given PID runs on this host
+func processExists(pid int) bool {
+	// on Windows, FindProcess fails if there is no such process
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
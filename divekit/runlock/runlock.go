@@ -0,0 +1,209 @@
+package runlock
+
+/**
+ * This file an "object-oriented lookalike" implementation for the run lock: an advisory lock file in the
+ * Divekit home dir, which the commands that change the tool repos in place (--in-place, restore) or the
+ * distributions of an origin repo hold while they run. This keeps two runs from writing the configs of the
+ * tools, the input dir of the Repo Editor, or the distributions at the same time. A patch run in a workspace
+ * only holds it while it writes into the origin repo. The lock file says who holds it, so that a stale lock of
+ * a crashed run can be detected and removed.
+ */
+
+import (
+	"divekit-cli/divekit"
+	"divekit-cli/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/apex/log"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// A lock held by a process on another host (e.g. with the home dir on a network share) can't be checked,
+// so it only counts as stale after this time
+const MaxForeignLockAge = 24 * time.Hour
+
+// how long a lock file without a holder is given before it counts as stale
+const incompleteLockGracePeriod = 10 * time.Second
+
+// how often AcquireWaiting tries to acquire the lock
+const acquireRetryInterval = 500 * time.Millisecond
+
+// the holder of the lock
+type HolderType struct {
+	PID       int       `json:"pid"`
+	Host      string    `json:"host"`
+	Command   string    `json:"command"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+type RunLockType struct {
+	FilePath string
+	Content  HolderType
+}
+
+// The path of the lock file
+func LockFilePath() string {
+	return filepath.Join(divekit.DivekitHomeDir, ".divekit-cli", "run.lock")
+}
+
+// This method is similar to a constructor in OOP. It acquires the run lock for the given command. A stale
+// lock is removed first. If another run holds the lock, an error of class utils.ErrLocked is returned.
+func Acquire(command string) (*RunLockType, error) {
+	log.Debug("runlock.Acquire() - command: " + command)
+	host, _ := os.Hostname()
+	runLock := &RunLockType{
+		FilePath: LockFilePath(),
+		Content: HolderType{
+			PID:       os.Getpid(),
+			Host:      host,
+			Command:   command,
+			StartedAt: time.Now(),
+		},
+	}
+	err := os.MkdirAll(filepath.Dir(runLock.FilePath), 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the dir of the lock file: %v", err)
+	}
+	content, err := json.MarshalIndent(runLock.Content, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the lock file: %v", err)
+	}
+
+	// a second attempt is only made after a stale lock has been removed
+	for attempt := 0; attempt < 2; attempt++ {
+		err = writeExclusively(runLock.FilePath, content)
+		if !errors.Is(err, fs.ErrExist) {
+			break
+		}
+		existingLock, readErr := Load()
+		if readErr != nil {
+			return nil, readErr
+		}
+		if existingLock == nil {
+			continue // released in the meantime
+		}
+		if stale, reason := existingLock.IsStale(); stale {
+			log.Warn(fmt.Sprintf("Removing the stale run lock of %s (%s)", existingLock.Describe(), reason))
+			if err = existingLock.Remove(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return nil, utils.NewError(utils.ErrLocked, "The Divekit home dir is locked by %s.\n"+
+			"Please wait until it has finished. If it doesn't run anymore, remove the lock with "+
+			"'divekit unlock --force'.", existingLock.Describe())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the lock file %s: %v", runLock.FilePath, err)
+	}
+	log.Debug("Acquired the run lock " + runLock.FilePath)
+	return runLock, nil
+}
+
+// Like Acquire, but if another run holds the lock, tries again until maxWait has passed
+func AcquireWaiting(command string, maxWait time.Duration) (*RunLockType, error) {
+	log.Debug("runlock.AcquireWaiting() - command: " + command)
+	deadline := time.Now().Add(maxWait)
+	for {
+		runLock, err := Acquire(command)
+		if !errors.Is(err, utils.ErrLocked) || time.Now().After(deadline) {
+			return runLock, err
+		}
+		time.Sleep(acquireRetryInterval)
+	}
+}
+
+// Loads the current lock, or returns nil if there is none
+func Load() (*RunLockType, error) {
+	log.Debug("runlock.Load()")
+	runLock := &RunLockType{FilePath: LockFilePath()}
+	content, err := os.ReadFile(runLock.FilePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the lock file %s: %v", runLock.FilePath, err)
+	}
+	if err = json.Unmarshal(content, &runLock.Content); err != nil {
+		// e.g. a run that crashed while writing the file - treated as stale, see IsStale
+		log.Warnf("The lock file %s can't be read: %v", runLock.FilePath, err)
+	}
+	return runLock, nil
+}
+
+// Checks if the holder of the lock doesn't run anymore. Also returns the reason why the lock is stale.
+func (runLock *RunLockType) IsStale() (bool, string) {
+	holder := runLock.Content
+	if holder.PID <= 0 {
+		// another run may just be writing the file
+		fileInfo, err := os.Stat(runLock.FilePath)
+		if err == nil && time.Since(fileInfo.ModTime()) < incompleteLockGracePeriod {
+			return false, ""
+		}
+		return true, "the lock file is incomplete"
+	}
+	host, _ := os.Hostname()
+	if holder.Host == host {
+		if !processExists(holder.PID) {
+			return true, fmt.Sprintf("process %d doesn't run anymore", holder.PID)
+		}
+		return false, ""
+	}
+	if time.Since(holder.StartedAt) > MaxForeignLockAge {
+		return true, fmt.Sprintf("it is older than %v", MaxForeignLockAge)
+	}
+	return false, ""
+}
+
+// Describes the holder of the lock, e.g. "'divekit patch' (PID 4711 on host pc-01, since 2023-05-04 10:11:12)"
+func (runLock *RunLockType) Describe() string {
+	holder := runLock.Content
+	return fmt.Sprintf("'divekit %s' (PID %d on host %s, since %s)", holder.Command, holder.PID, holder.Host,
+		holder.StartedAt.Format("2006-01-02 15:04:05"))
+}
+
+// Releases the lock, if it is still held by this run
+func (runLock *RunLockType) Release() error {
+	log.Debug("runlock.Release()")
+	currentLock, err := Load()
+	if err != nil {
+		return err
+	}
+	if currentLock == nil || currentLock.Content.PID != runLock.Content.PID ||
+		!currentLock.Content.StartedAt.Equal(runLock.Content.StartedAt) {
+		log.Warn("The run lock has been removed or taken over by another run in the meantime")
+		return nil
+	}
+	return runLock.Remove()
+}
+
+// Removes the lock file, regardless of who holds the lock
+func (runLock *RunLockType) Remove() error {
+	log.Debug("runlock.Remove() - filePath: " + runLock.FilePath)
+	err := os.Remove(runLock.FilePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove the lock file %s: %v", runLock.FilePath, err)
+	}
+	return nil
+}
+
+// Creates the file with the given content, failing with fs.ErrExist if it exists already
+func writeExclusively(filePath string, content []byte) error {
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(content)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(filePath)
+	}
+	return err
+}
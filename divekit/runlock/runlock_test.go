@@ -0,0 +1,46 @@
+package runlock
+
+import (
+	"divekit-cli/divekit"
+	"divekit-cli/utils"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// Sets the home dir to a temporary dir for the duration of a test
+func useTemporaryHomeDir(t *testing.T) {
+	previousHomeDir := divekit.DivekitHomeDir
+	t.Cleanup(func() { divekit.DivekitHomeDir = previousHomeDir })
+	divekit.DivekitHomeDir = t.TempDir()
+}
+
+func TestAcquireWaiting(t *testing.T) {
+	useTemporaryHomeDir(t)
+	// held by this process, so it isn't stale
+	firstLock, err := Acquire("distribute")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = AcquireWaiting("patch", 0)
+	if !errors.Is(err, utils.ErrLocked) {
+		t.Fatalf("expected an error of class %s, got %v", utils.ErrLocked.Name, err)
+	}
+
+	go func() {
+		time.Sleep(2 * acquireRetryInterval)
+		firstLock.Release()
+	}()
+	secondLock, err := AcquireWaiting("patch", time.Minute)
+	if err != nil {
+		t.Fatalf("expected the lock after it has been released, got %v", err)
+	}
+	if secondLock.Content.Command != "patch" || secondLock.Content.PID != os.Getpid() {
+		t.Errorf("expected the lock to be held by this 'patch' run, got %s", secondLock.Describe())
+	}
+	if err = secondLock.Release(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
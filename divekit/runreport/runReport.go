@@ -0,0 +1,308 @@
+package runreport
+
+/**
+ * This file an "object-oriented lookalike" implementation for the report of a run of the CLI: what was done
+ * with which files, how long each phase took, what became of each repo, and the warnings of the run. The
+ * report is written as JSON for scripts, and can be rendered as Markdown, e.g. to post it in a team channel.
+ */
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/apex/log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// the outcome of a single repo
+const (
+	RepoPatched    = "patched"     // the Repo Editor reported the repo without an error
+	RepoFailed     = "failed"      // the Repo Editor reported an error for the repo
+	RepoUnknown    = "unknown"     // the Repo Editor didn't mention the repo
+	RepoNotPatched = "not patched" // the Repo Editor wasn't run (dry run, preview, or an earlier failure)
+)
+
+// the outcome of a phase
+const (
+	PhaseOK     = "ok"
+	PhaseFailed = "failed"
+)
+
+type PhaseType struct {
+	Name            string    `json:"name"`
+	StartedAt       time.Time `json:"startedAt"`
+	DurationSeconds float64   `json:"durationSeconds"`
+	Outcome         string    `json:"outcome"`
+}
+
+type RepoType struct {
+	Name     string   `json:"name"` // relative to the local output of the ARS, e.g. "code/st2-m3-1a2b"
+	Outcome  string   `json:"outcome"`
+	Messages []string `json:"messages,omitempty"` // the lines of the Repo Editor's output about the repo
+}
+
+type RunReportType struct {
+	Content struct {
+		Command            string      `json:"command"`
+		CLIVersion         string      `json:"cliVersion"`
+		Distribution       string      `json:"distribution"`
+		Target             string      `json:"target,omitempty"`
+		DryRun             bool        `json:"dryRun"`
+		Preview            bool        `json:"preview"`
+		StartedAt          time.Time   `json:"startedAt"`
+		DurationSeconds    float64     `json:"durationSeconds"`
+		Outcome            string      `json:"outcome"`
+		ExitCode           int         `json:"exitCode"`
+		Error              string      `json:"error,omitempty"`
+		InterruptedPhase   string      `json:"interruptedPhase,omitempty"`
+		PatchFiles         []string    `json:"patchFiles"`
+		CommitMsg          string      `json:"commitMsg,omitempty"`
+		GeneratedRepoCount int         `json:"generatedRepoCount"`
+		Repos              []RepoType  `json:"repos"`
+		Phases             []PhaseType `json:"phases"`
+		Warnings           []string    `json:"warnings"`
+		// the warnings and errors in the output of the tools, at most maxToolWarnings
+		ToolWarnings        []string `json:"toolWarnings"`
+		OmittedToolWarnings int      `json:"omittedToolWarnings,omitempty"`
+		ToolLogFile         string   `json:"toolLogFile,omitempty"` // the full output of the ARS and Repo Editor
+	}
+	currentPhase *PhaseType
+}
+
+// This method is similar to a constructor in OOP
+func NewRunReport(command, cliVersion string) *RunReportType {
+	log.Debug("runreport.NewRunReport() - command: " + command)
+	runReport := &RunReportType{}
+	runReport.Content.Command = command
+	runReport.Content.CLIVersion = cliVersion
+	runReport.Content.StartedAt = time.Now()
+	runReport.Content.PatchFiles = []string{}
+	runReport.Content.Repos = []RepoType{}
+	runReport.Content.Phases = []PhaseType{}
+	runReport.Content.Warnings = []string{}
+	runReport.Content.ToolWarnings = []string{}
+	return runReport
+}
+
+// Starts a new phase of the run, which ends the current one successfully
+func (runReport *RunReportType) StartPhase(name string) {
+	runReport.EndPhase(PhaseOK)
+	runReport.Content.Phases = append(runReport.Content.Phases, PhaseType{Name: name, StartedAt: time.Now()})
+	runReport.currentPhase = &runReport.Content.Phases[len(runReport.Content.Phases)-1]
+}
+
+// Ends the current phase, if there is one, with the given outcome
+func (runReport *RunReportType) EndPhase(outcome string) {
+	if runReport.currentPhase == nil {
+		return
+	}
+	runReport.currentPhase.DurationSeconds = roundSeconds(time.Since(runReport.currentPhase.StartedAt))
+	runReport.currentPhase.Outcome = outcome
+	runReport.currentPhase = nil
+}
+
+func (runReport *RunReportType) AddWarning(warning string) {
+	runReport.Content.Warnings = append(runReport.Content.Warnings, warning)
+}
+
+// how many warnings from the output of the tools are kept, as e.g. npm can produce a lot of them
+const maxToolWarnings = 20
+
+// Adds a warning or error from the output of a tool. Only the first maxToolWarnings are kept, the others
+// are counted.
+func (runReport *RunReportType) AddToolWarning(warning string) {
+	if len(runReport.Content.ToolWarnings) >= maxToolWarnings {
+		runReport.Content.OmittedToolWarnings++
+		return
+	}
+	runReport.Content.ToolWarnings = append(runReport.Content.ToolWarnings, warning)
+}
+
+// Sets the repos generated by the ARS, all with the same outcome
+func (runReport *RunReportType) SetGeneratedRepos(repoNames []string, outcome string) {
+	runReport.Content.GeneratedRepoCount = len(repoNames)
+	runReport.Content.Repos = []RepoType{}
+	for _, repoName := range repoNames {
+		runReport.Content.Repos = append(runReport.Content.Repos, RepoType{Name: repoName, Outcome: outcome})
+	}
+}
+
+// Determines the outcome of each repo from the output of the Repo Editor: a repo is failed if a line that
+// mentions it also mentions an error, patched if it is mentioned otherwise, and unknown if it isn't mentioned.
+func (runReport *RunReportType) EvaluateRepoEditorOutput(output string) {
+	log.Debug("runreport.EvaluateRepoEditorOutput()")
+	lines := strings.Split(output, "\n")
+	for index := range runReport.Content.Repos {
+		repo := &runReport.Content.Repos[index]
+		repoId := repo.Name[strings.LastIndex(repo.Name, "/")+1:]
+		repo.Outcome = RepoUnknown
+		repo.Messages = nil
+		for _, line := range lines {
+			if !containsWord(line, repoId) {
+				continue
+			}
+			repo.Messages = append(repo.Messages, strings.TrimSpace(line))
+			lowerLine := strings.ToLower(line)
+			if strings.Contains(lowerLine, "error") || strings.Contains(lowerLine, "fail") {
+				repo.Outcome = RepoFailed
+			} else if repo.Outcome == RepoUnknown {
+				repo.Outcome = RepoPatched
+			}
+		}
+	}
+}
+
+// Ends the run with the given outcome. The exit code and error are those the program ends with.
+func (runReport *RunReportType) Finish(outcome string, exitCode int, err error) {
+	phaseOutcome := PhaseOK
+	if exitCode != 0 || err != nil {
+		phaseOutcome = PhaseFailed
+	}
+	runReport.EndPhase(phaseOutcome)
+	runReport.Content.Outcome = outcome
+	runReport.Content.ExitCode = exitCode
+	if err != nil {
+		runReport.Content.Error = err.Error()
+	}
+	runReport.Content.DurationSeconds = roundSeconds(time.Since(runReport.Content.StartedAt))
+}
+
+// Writes the report as JSON
+func (runReport *RunReportType) WriteJSON(filePath string) error {
+	log.Debug("runreport.WriteJSON() - filePath: " + filePath)
+	content, err := json.MarshalIndent(runReport.Content, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal the run report: %v", err)
+	}
+	err = os.WriteFile(filePath, append(content, '\n'), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write the run report %s: %v", filePath, err)
+	}
+	return nil
+}
+
+// Writes the report as Markdown
+func (runReport *RunReportType) WriteMarkdown(filePath string) error {
+	log.Debug("runreport.WriteMarkdown() - filePath: " + filePath)
+	err := os.WriteFile(filePath, []byte(runReport.Markdown()), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write the run report %s: %v", filePath, err)
+	}
+	return nil
+}
+
+// Renders the report as Markdown
+func (runReport *RunReportType) Markdown() string {
+	content := &runReport.Content
+	builder := &strings.Builder{}
+	fmt.Fprintf(builder, "## divekit %s of distribution %s: %s\n\n", content.Command, content.Distribution,
+		content.Outcome)
+	fmt.Fprintf(builder, "- Started: %s, took %.1f s\n", content.StartedAt.Format("2006-01-02 15:04:05"),
+		content.DurationSeconds)
+	if content.Target != "" {
+		fmt.Fprintf(builder, "- Target: %s repos\n", content.Target)
+	}
+	if content.CommitMsg != "" {
+		fmt.Fprintf(builder, "- Commit message: %s\n", firstLine(content.CommitMsg))
+	}
+	if content.DryRun {
+		builder.WriteString("- Dry run: the repos were not changed\n")
+	}
+	if content.Error != "" {
+		fmt.Fprintf(builder, "- Error (exit code %d): %s\n", content.ExitCode, firstLine(content.Error))
+	}
+	if content.InterruptedPhase != "" {
+		fmt.Fprintf(builder, "- Interrupted during phase: %s\n", content.InterruptedPhase)
+	}
+	fmt.Fprintf(builder, "- CLI version: %s\n", content.CLIVersion)
+	if content.ToolLogFile != "" {
+		fmt.Fprintf(builder, "- Output of the tools: `%s`\n", content.ToolLogFile)
+	}
+
+	fmt.Fprintf(builder, "\n### Files (%d)\n\n", len(content.PatchFiles))
+	for _, patchFile := range content.PatchFiles {
+		fmt.Fprintf(builder, "- `%s`\n", patchFile)
+	}
+
+	fmt.Fprintf(builder, "\n### Repos (%d generated)\n\n", content.GeneratedRepoCount)
+	if len(content.Repos) > 0 {
+		builder.WriteString("| Outcome | Repos |\n|---|---|\n")
+		reposByOutcome := make(map[string][]string)
+		for _, repo := range content.Repos {
+			reposByOutcome[repo.Outcome] = append(reposByOutcome[repo.Outcome], "`"+repo.Name+"`")
+		}
+		outcomes := make([]string, 0, len(reposByOutcome))
+		for outcome := range reposByOutcome {
+			outcomes = append(outcomes, outcome)
+		}
+		sort.Strings(outcomes)
+		for _, outcome := range outcomes {
+			fmt.Fprintf(builder, "| %s (%d) | %s |\n", outcome, len(reposByOutcome[outcome]),
+				strings.Join(reposByOutcome[outcome], ", "))
+		}
+	}
+
+	builder.WriteString("\n### Phases\n\n| Phase | Duration | Outcome |\n|---|---|---|\n")
+	for _, phase := range content.Phases {
+		fmt.Fprintf(builder, "| %s | %.1f s | %s |\n", phase.Name, phase.DurationSeconds, phase.Outcome)
+	}
+
+	if len(content.Warnings) > 0 {
+		fmt.Fprintf(builder, "\n### Warnings (%d)\n\n", len(content.Warnings))
+		for _, warning := range content.Warnings {
+			fmt.Fprintf(builder, "- %s\n", firstLine(warning))
+		}
+	}
+	if len(content.ToolWarnings) > 0 {
+		fmt.Fprintf(builder, "\n### Warnings in the tool output (%d)\n\n",
+			len(content.ToolWarnings)+content.OmittedToolWarnings)
+		for _, warning := range content.ToolWarnings {
+			fmt.Fprintf(builder, "- %s\n", firstLine(warning))
+		}
+		if content.OmittedToolWarnings > 0 {
+			fmt.Fprintf(builder, "- ... and %d more, see the tool log file\n", content.OmittedToolWarnings)
+		}
+	}
+	return builder.String()
+}
+
+// Returns the repos with the given outcome
+func (runReport *RunReportType) ReposWithOutcome(outcome string) []string {
+	repoNames := []string{}
+	for _, repo := range runReport.Content.Repos {
+		if repo.Outcome == outcome {
+			repoNames = append(repoNames, repo.Name)
+		}
+	}
+	return repoNames
+}
+
+// Checks if text contains word, not as part of a longer name (e.g. "r1" is not found in "r10")
+func containsWord(text, word string) bool {
+	isNamePart := func(char byte) bool {
+		return char == '-' || char == '_' || char >= '0' && char <= '9' || char >= 'a' && char <= 'z' ||
+			char >= 'A' && char <= 'Z'
+	}
+	for start := 0; ; {
+		index := strings.Index(text[start:], word)
+		if index < 0 {
+			return false
+		}
+		index += start
+		end := index + len(word)
+		if (index == 0 || !isNamePart(text[index-1])) && (end == len(text) || !isNamePart(text[end])) {
+			return true
+		}
+		start = index + 1
+	}
+}
+
+func roundSeconds(duration time.Duration) float64 {
+	return float64(duration.Round(time.Millisecond).Milliseconds()) / 1000
+}
+
+func firstLine(text string) string {
+	return strings.SplitN(strings.TrimSpace(text), "\n", 2)[0]
+}
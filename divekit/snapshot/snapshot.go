@@ -0,0 +1,165 @@
+package snapshot
+
+/**
+ * This file an "object-oriented lookalike" implementation for snapshots of files and directories in the
+ * ARS and Repo Editor repos, which the CLI overwrites during a run. A snapshot is taken before a run and
+ * restored afterwards, so that a local setup of the tools is not clobbered. Snapshots are stored in the
+ * Divekit home dir; if a run crashes, the remaining snapshot can be restored via "divekit restore".
+ */
+
+import (
+	"divekit-cli/divekit"
+	"divekit-cli/utils"
+	"encoding/json"
+	"fmt"
+	"github.com/apex/log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const manifestFileName = "manifest.json"
+
+// one file or directory saved in a snapshot
+type SnapshotEntry struct {
+	OriginalPath string `json:"originalPath"`
+	SnapshotPath string `json:"snapshotPath"`
+	IsDir        bool   `json:"isDir"`
+	Existed      bool   `json:"existed"`
+}
+
+type SnapshotType struct {
+	Dir     string
+	Content struct {
+		CreatedAt time.Time       `json:"createdAt"`
+		Command   string          `json:"command"`
+		Entries   []SnapshotEntry `json:"entries"`
+	}
+}
+
+// The directory where all snapshots are stored
+func SnapshotsRootDir() string {
+	return filepath.Join(divekit.DivekitHomeDir, ".divekit-cli", "snapshots")
+}
+
+// This method is similar to a constructor in OOP. It copies the given files and directories into a new
+// snapshot. Paths that don't exist yet are recorded as well, so that they are removed again on restore.
+func NewSnapshot(command string, paths ...string) (*SnapshotType, error) {
+	log.Debug("snapshot.NewSnapshot()")
+	snapshot := &SnapshotType{}
+	snapshot.Content.CreatedAt = time.Now()
+	snapshot.Content.Command = command
+	snapshot.Dir = filepath.Join(SnapshotsRootDir(), snapshot.Content.CreatedAt.Format("20060102-150405.000000000"))
+	err := os.MkdirAll(snapshot.Dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot dir: %v", err)
+	}
+
+	for index, path := range paths {
+		entry := SnapshotEntry{
+			OriginalPath: path,
+			SnapshotPath: filepath.Join(snapshot.Dir, fmt.Sprintf("%02d-%s", index, filepath.Base(path))),
+		}
+		fileInfo, err := os.Stat(path)
+		if err == nil {
+			entry.Existed = true
+			entry.IsDir = fileInfo.IsDir()
+			err = copyPath(path, entry.SnapshotPath, entry.IsDir)
+		} else if os.IsNotExist(err) {
+			err = nil
+		}
+		if err != nil {
+			snapshot.Remove()
+			return nil, fmt.Errorf("failed to save %s in snapshot: %v", path, err)
+		}
+		snapshot.Content.Entries = append(snapshot.Content.Entries, entry)
+	}
+
+	err = snapshot.writeManifest()
+	if err != nil {
+		snapshot.Remove()
+		return nil, err
+	}
+	log.Info("Saved a snapshot of the tool configs in " + snapshot.Dir)
+	return snapshot, nil
+}
+
+// Loads the most recent snapshot that has not been restored yet, or returns nil if there is none
+func LoadLatestSnapshot() (*SnapshotType, error) {
+	log.Debug("snapshot.LoadLatestSnapshot()")
+	snapshotDirs, err := utils.ListSubfolderNames(SnapshotsRootDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %v", err)
+	}
+	if len(snapshotDirs) == 0 {
+		return nil, nil
+	}
+	sort.Strings(snapshotDirs)
+	snapshot := &SnapshotType{Dir: filepath.Join(SnapshotsRootDir(), snapshotDirs[len(snapshotDirs)-1])}
+	content, err := os.ReadFile(filepath.Join(snapshot.Dir, manifestFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot manifest: %v", err)
+	}
+	err = json.Unmarshal(content, &snapshot.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal snapshot manifest: %v", err)
+	}
+	return snapshot, nil
+}
+
+// Puts all saved files and directories back in place, and removes the snapshot afterwards
+func (snapshot *SnapshotType) Restore() error {
+	log.Debug("snapshot.Restore() - dir: " + snapshot.Dir)
+	var errorsList []error
+	for _, entry := range snapshot.Content.Entries {
+		err := os.RemoveAll(entry.OriginalPath)
+		if err == nil && entry.Existed {
+			err = copyPath(entry.SnapshotPath, entry.OriginalPath, entry.IsDir)
+		}
+		if err != nil {
+			errorsList = append(errorsList, fmt.Errorf("failed to restore %s: %v", entry.OriginalPath, err))
+		}
+	}
+	if len(errorsList) > 0 {
+		// keep the snapshot, so that the restore can be retried
+		return fmt.Errorf("%v", errorsList)
+	}
+	log.Info("Restored the tool configs from snapshot " + snapshot.Dir)
+	return snapshot.Remove()
+}
+
+func (snapshot *SnapshotType) Remove() error {
+	log.Debug("snapshot.Remove() - dir: " + snapshot.Dir)
+	return os.RemoveAll(snapshot.Dir)
+}
+
+func (snapshot *SnapshotType) writeManifest() error {
+	content, err := json.MarshalIndent(snapshot.Content, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot manifest: %v", err)
+	}
+	err = os.WriteFile(filepath.Join(snapshot.Dir, manifestFileName), content, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write snapshot manifest: %v", err)
+	}
+	return nil
+}
+
+func copyPath(srcPath, destPath string, isDir bool) error {
+	if isDir {
+		err := os.MkdirAll(destPath, 0755)
+		if err != nil {
+			return err
+		}
+		return utils.CopyAllFilesInDir(srcPath, destPath)
+	}
+	err := os.MkdirAll(filepath.Dir(destPath), 0755)
+	if err != nil {
+		return err
+	}
+	return utils.CopyFileTo(srcPath, destPath)
+}
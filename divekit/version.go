@@ -0,0 +1,5 @@
+package divekit
+
+// Version of the CLI, recorded e.g. in the patch history. Can be set at build time via
+// go build -ldflags "-X divekit-cli/divekit.Version=1.2.3"
+var Version = "dev"
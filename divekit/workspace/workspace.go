@@ -0,0 +1,127 @@
+package workspace
+
+/**
+ * This file an "object-oriented lookalike" implementation for a run workspace: a temporary copy of the
+ * tool repos (ARS, Repo Editor) in which a single run of the CLI writes its configs, inputs and outputs.
+ * This keeps the tool repos in the Divekit home dir pristine, and allows several runs at the same time.
+ * The node_modules folders are not copied, but linked.
+ */
+
+import (
+	"divekit-cli/divekit"
+	"divekit-cli/utils"
+	"fmt"
+	"github.com/apex/log"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+type WorkspaceType struct {
+	Dir string
+}
+
+// The directory where all run workspaces are created
+func WorkspacesRootDir() string {
+	return filepath.Join(divekit.DivekitHomeDir, ".divekit-cli", "workspaces")
+}
+
+// This method is similar to a constructor in OOP. It creates a new workspace and copies the given tool repos
+// into it, leaving out their .git folders. The contents of the directories in emptyDirs (relative to each tool
+// repo) are not copied, so that e.g. outputs of previous runs don't leak into this run.
+func NewWorkspace(toolRepoDirs []string, emptyDirs []string) (*WorkspaceType, error) {
+	log.Debug("workspace.NewWorkspace()")
+	err := os.MkdirAll(WorkspacesRootDir(), 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspaces dir: %v", err)
+	}
+	workspaceDir, err := os.MkdirTemp(WorkspacesRootDir(), "run-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create run workspace: %v", err)
+	}
+	workspace := &WorkspaceType{Dir: workspaceDir}
+
+	for _, toolRepoDir := range toolRepoDirs {
+		err = copyToolRepo(toolRepoDir, workspace.ToolRepoDir(toolRepoDir), emptyDirs)
+		if err != nil {
+			workspace.Remove()
+			return nil, fmt.Errorf("failed to copy %s into the run workspace: %v", toolRepoDir, err)
+		}
+	}
+	log.Info("Created run workspace " + workspace.Dir)
+	return workspace, nil
+}
+
+// Returns the directory of the copy of a tool repo in the workspace
+func (workspace *WorkspaceType) ToolRepoDir(originalToolRepoDir string) string {
+	return filepath.Join(workspace.Dir, filepath.Base(originalToolRepoDir))
+}
+
+func (workspace *WorkspaceType) Remove() error {
+	log.Debug("workspace.Remove() - dir: " + workspace.Dir)
+	err := os.RemoveAll(workspace.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to remove run workspace %s: %v", workspace.Dir, err)
+	}
+	log.Info("Removed run workspace " + workspace.Dir)
+	return nil
+}
+
+func copyToolRepo(srcDir, destDir string, emptyDirs []string) error {
+	return filepath.WalkDir(srcDir, func(srcPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(srcDir, srcPath)
+		if err != nil {
+			return err
+		}
+		destPath := filepath.Join(destDir, relPath)
+		if !entry.IsDir() {
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+			return utils.CopyFileTo(srcPath, destPath)
+		}
+
+		switch {
+		case entry.Name() == ".git":
+			return filepath.SkipDir
+		case entry.Name() == "node_modules":
+			return linkNodeModules(srcPath, destPath)
+		case isOneOf(relPath, emptyDirs):
+			err = os.MkdirAll(destPath, 0755)
+			if err != nil {
+				return err
+			}
+			return filepath.SkipDir
+		}
+		return os.MkdirAll(destPath, 0755)
+	})
+}
+
+// Links the node_modules folder into the workspace. If symlinks are not permitted (e.g. on Windows
+// without developer mode), the folder is copied instead, which is slow but works.
+func linkNodeModules(srcPath, destPath string) error {
+	err := os.Symlink(srcPath, destPath)
+	if err != nil {
+		log.Warnf("Could not link %s (%v), copying it instead", srcPath, err)
+		err = os.MkdirAll(destPath, 0755)
+		if err == nil {
+			err = utils.CopyAllFilesInDir(srcPath, destPath)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return filepath.SkipDir
+}
+
+func isOneOf(relPath string, relPaths []string) bool {
+	for _, candidate := range relPaths {
+		if filepath.Clean(candidate) == relPath {
+			return true
+		}
+	}
+	return false
+}
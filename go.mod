@@ -2,10 +2,13 @@ module divekit-cli
 
 go 1.20
 
-require github.com/spf13/cobra v1.7.0
+require (
+	github.com/apex/log v1.9.0
+	github.com/spf13/cobra v1.7.0
+	golang.org/x/text v0.9.0
+)
 
 require (
-	github.com/apex/log v1.9.0 // indirect
 	github.com/fsnotify/fsnotify v1.6.0 // indirect
 	github.com/hashicorp/hcl v1.0.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
@@ -20,7 +23,6 @@ require (
 	github.com/spf13/viper v1.15.0 // indirect
 	github.com/subosito/gotenv v1.4.2 // indirect
 	golang.org/x/sys v0.5.0 // indirect
-	golang.org/x/text v0.9.0 // indirect
 	gopkg.in/ini.v1 v1.67.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
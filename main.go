@@ -2,16 +2,19 @@ package main
 
 import (
 	"divekit-cli/cmd"
-	"fmt"
+	"divekit-cli/utils"
 	"github.com/apex/log"
-	"os"
 )
 
 func main() {
 	log.Debug("main()")
+	utils.HandleInterrupts()
 	err := cmd.Execute()
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		// errors that cobra returns itself are about the command line
+		err = utils.ClassifyError(utils.ErrUsage, err)
+		utils.OutputError(err)
+		utils.Exit(utils.ExitCode(err))
 	}
+	utils.RunCleanups()
 }
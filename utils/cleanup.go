@@ -0,0 +1,80 @@
+package utils
+
+/**
+ * This file contains a registry of cleanup functions, which are run when the program ends - regularly,
+ * via Exit(), or via log.Fatal (see CustomHandler).
+ */
+
+import (
+	"errors"
+	"github.com/apex/log"
+	"os"
+	"sync"
+)
+
+var (
+	cleanupMutex sync.Mutex
+	cleanupFuncs []func()
+	// held while the cleanups run, so that an Exit from another goroutine (e.g. on an interrupt) waits for them
+	cleanupRunMutex sync.Mutex
+	// why the program ends early, see ExitReason
+	exitCode  int
+	exitError error
+)
+
+// Registers a function to be run at the end of the program. Cleanups run in reverse order of registration.
+func RegisterCleanup(cleanupFunc func()) {
+	cleanupMutex.Lock()
+	defer cleanupMutex.Unlock()
+	cleanupFuncs = append(cleanupFuncs, cleanupFunc)
+}
+
+// Runs all registered cleanup functions (each only once). If the run has phases, they run in the cleanup phase.
+func RunCleanups() {
+	cleanupRunMutex.Lock()
+	defer cleanupRunMutex.Unlock()
+	cleanupMutex.Lock()
+	funcs := cleanupFuncs
+	cleanupFuncs = nil
+	cleanupMutex.Unlock()
+	if len(funcs) > 0 {
+		log.Debug("utils.RunCleanups()")
+		if CurrentPhase() != "" {
+			setPhase(CleanupPhase)
+		}
+	}
+	for index := len(funcs) - 1; index >= 0; index-- {
+		funcs[index]()
+	}
+}
+
+// Runs the cleanup functions, and exits the program with the given code
+func Exit(code int) {
+	setExitReason(code, nil)
+	RunCleanups()
+	os.Exit(code)
+}
+
+// Returns the exit code and the error (if known) the program ends with, for the cleanups. Both are zero
+// if the program ends regularly.
+func ExitReason() (int, error) {
+	cleanupMutex.Lock()
+	defer cleanupMutex.Unlock()
+	return exitCode, exitError
+}
+
+// Records why the program ends early. The first reason wins, e.g. the error before the exit code.
+func setExitReason(code int, err error) {
+	cleanupMutex.Lock()
+	defer cleanupMutex.Unlock()
+	if exitCode == 0 {
+		exitCode = code
+	}
+	if exitError == nil {
+		exitError = err
+	}
+}
+
+func setExitMessage(code int, message string) {
+	setExitReason(code, errors.New(message))
+}
@@ -0,0 +1,229 @@
+package utils
+
+/**
+ * This file contains a small line-based unified diff implementation, used to preview changes.
+ * Lines common to the start and end of both texts are skipped first; the rest is diffed via the longest
+ * common subsequence, unless it is too large - then it is shown as removed and added as a whole.
+ * Lines are compared with their line endings, so that a change of only the line endings or the final newline
+ * shows up, too: a carriage return is shown as "^M", and a missing final newline as in "diff -u".
+ */
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContextLines = 3
+
+// The maximum size of the table for the longest common subsequence (lines of the one text times lines of the
+// other), about 32 MB. Larger changed blocks are shown as removed and added as a whole.
+const maxDiffTableCells = 4_000_000
+
+type diffOp struct {
+	kind byte   // ' ', '-' or '+'
+	line string // with its line ending, if it has one
+}
+
+// Returns a unified diff (like "diff -u") between two texts, or an empty string if they are equal
+func UnifiedDiff(fromName, toName, fromText, toText string) string {
+	if fromText == toText {
+		return ""
+	}
+	ops := diffLines(splitLines(fromText), splitLines(toText))
+	if !hasChanges(ops) {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", fromName, toName))
+	for start := 0; start < len(ops); {
+		// find the next change
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		hunkStart := maxInt(0, start-diffContextLines)
+		// extend the hunk as long as changes are closer than twice the context
+		end := start
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			unchanged := 0
+			for end+unchanged < len(ops) && ops[end+unchanged].kind == ' ' {
+				unchanged++
+			}
+			if end+unchanged == len(ops) || unchanged > 2*diffContextLines {
+				end = minInt(len(ops), end+diffContextLines)
+				break
+			}
+			end += unchanged
+		}
+		writeHunk(&builder, ops, hunkStart, end)
+		start = end
+	}
+	return builder.String()
+}
+
+func hasChanges(ops []diffOp) bool {
+	for _, op := range ops {
+		if op.kind != ' ' {
+			return true
+		}
+	}
+	return false
+}
+
+func writeHunk(builder *strings.Builder, ops []diffOp, hunkStart, hunkEnd int) {
+	fromLine, toLine := 1, 1
+	for _, op := range ops[:hunkStart] {
+		if op.kind != '+' {
+			fromLine++
+		}
+		if op.kind != '-' {
+			toLine++
+		}
+	}
+	fromCount, toCount := 0, 0
+	for _, op := range ops[hunkStart:hunkEnd] {
+		if op.kind != '+' {
+			fromCount++
+		}
+		if op.kind != '-' {
+			toCount++
+		}
+	}
+	builder.WriteString(fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(fromLine, fromCount), hunkRange(toLine, toCount)))
+	for _, op := range ops[hunkStart:hunkEnd] {
+		builder.WriteByte(op.kind)
+		line := strings.TrimSuffix(op.line, "\n")
+		if strings.HasSuffix(line, "\r") {
+			line = strings.TrimSuffix(line, "\r") + "^M"
+		}
+		builder.WriteString(line)
+		builder.WriteByte('\n')
+		if !strings.HasSuffix(op.line, "\n") {
+			builder.WriteString("\\ No newline at end of file\n")
+		}
+	}
+}
+
+// Formats a range of a hunk header like "diff -u": "start,count", just "start" for a single line, and for an
+// empty range the line after which it is, e.g. "0,0" at the start of the file
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// Computes the edit script between two line lists. The lines common to the start and end are kept as they
+// are, so that the (quadratic) longest common subsequence is only computed for the changed block in between.
+func diffLines(fromLines, toLines []string) []diffOp {
+	prefix := 0
+	for prefix < len(fromLines) && prefix < len(toLines) && fromLines[prefix] == toLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(fromLines)-prefix && suffix < len(toLines)-prefix &&
+		fromLines[len(fromLines)-1-suffix] == toLines[len(toLines)-1-suffix] {
+		suffix++
+	}
+
+	ops := []diffOp{}
+	for _, line := range fromLines[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	fromBlock, toBlock := fromLines[prefix:len(fromLines)-suffix], toLines[prefix:len(toLines)-suffix]
+	if len(fromBlock)*len(toBlock) > maxDiffTableCells {
+		ops = append(ops, replaceWholeBlock(fromBlock, toBlock)...)
+	} else {
+		ops = append(ops, diffLinesLCS(fromBlock, toBlock)...)
+	}
+	for _, line := range fromLines[len(fromLines)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// The edit script that removes all fromLines and adds all toLines
+func replaceWholeBlock(fromLines, toLines []string) []diffOp {
+	ops := []diffOp{}
+	for _, line := range fromLines {
+		ops = append(ops, diffOp{'-', line})
+	}
+	for _, line := range toLines {
+		ops = append(ops, diffOp{'+', line})
+	}
+	return ops
+}
+
+// Computes the edit script between two line lists via the longest common subsequence
+func diffLinesLCS(fromLines, toLines []string) []diffOp {
+	lcs := make([][]int, len(fromLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(toLines)+1)
+	}
+	for i := len(fromLines) - 1; i >= 0; i-- {
+		for j := len(toLines) - 1; j >= 0; j-- {
+			if fromLines[i] == toLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = maxInt(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := []diffOp{}
+	i, j := 0, 0
+	for i < len(fromLines) && j < len(toLines) {
+		switch {
+		case fromLines[i] == toLines[j]:
+			ops = append(ops, diffOp{' ', fromLines[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', fromLines[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', toLines[j]})
+			j++
+		}
+	}
+	for ; i < len(fromLines); i++ {
+		ops = append(ops, diffOp{'-', fromLines[i]})
+	}
+	for ; j < len(toLines); j++ {
+		ops = append(ops, diffOp{'+', toLines[j]})
+	}
+	return ops
+}
+
+// Splits a text into lines, each with its line ending. Only the last line may have none.
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	lines := func(from, to int) string {
+		var builder strings.Builder
+		for i := from; i <= to; i++ {
+			builder.WriteString(fmt.Sprintf("%d\n", i))
+		}
+		return builder.String()
+	}
+	tests := []struct {
+		name     string
+		fromText string
+		toText   string
+		expected string
+	}{
+		{
+			name:     "identical files",
+			fromText: "a\nb\nc\n",
+			toText:   "a\nb\nc\n",
+			expected: "",
+		},
+		{
+			name:     "empty files",
+			fromText: "",
+			toText:   "",
+			expected: "",
+		},
+		{
+			name:     "only line endings differ",
+			fromText: "a\r\nb\n",
+			toText:   "a\nb\n",
+			expected: "--- from\n+++ to\n@@ -1,2 +1,2 @@\n-a^M\n+a\n b\n",
+		},
+		{
+			name:     "final newline removed",
+			fromText: "a\nb\n",
+			toText:   "a\nb",
+			expected: "--- from\n+++ to\n@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n",
+		},
+		{
+			name:     "final newline added",
+			fromText: "a",
+			toText:   "a\n",
+			expected: "--- from\n+++ to\n@@ -1 +1 @@\n-a\n\\ No newline at end of file\n+a\n",
+		},
+		{
+			name:     "line added after a line without a newline",
+			fromText: "a\nb",
+			toText:   "a\nb\nc",
+			expected: "--- from\n+++ to\n@@ -1,2 +1,3 @@\n a\n-b\n\\ No newline at end of file\n+b\n+c\n" +
+				"\\ No newline at end of file\n",
+		},
+		{
+			name:     "added file",
+			fromText: "",
+			toText:   "a\nb\nc\n",
+			expected: "--- from\n+++ to\n@@ -0,0 +1,3 @@\n+a\n+b\n+c\n",
+		},
+		{
+			name:     "removed file",
+			fromText: "a\nb\nc\n",
+			toText:   "",
+			expected: "--- from\n+++ to\n@@ -1,3 +0,0 @@\n-a\n-b\n-c\n",
+		},
+		{
+			name:     "single line changed",
+			fromText: "a\n",
+			toText:   "b\n",
+			expected: "--- from\n+++ to\n@@ -1 +1 @@\n-a\n+b\n",
+		},
+		{
+			name:     "line changed in the middle",
+			fromText: "a\nb\nc\n",
+			toText:   "a\nB\nc\n",
+			expected: "--- from\n+++ to\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name:     "line removed",
+			fromText: lines(1, 5),
+			toText:   "1\n2\n4\n5\n",
+			expected: "--- from\n+++ to\n@@ -1,5 +1,4 @@\n 1\n 2\n-3\n 4\n 5\n",
+		},
+		{
+			name:     "line added",
+			fromText: lines(1, 5),
+			toText:   "1\n2\n3\nx\n4\n5\n",
+			expected: "--- from\n+++ to\n@@ -1,5 +1,6 @@\n 1\n 2\n 3\n+x\n 4\n 5\n",
+		},
+		{
+			name:     "multiple hunks",
+			fromText: lines(1, 20),
+			toText:   strings.Replace(strings.Replace(lines(1, 20), "\n2\n", "\ntwo\n", 1), "\n18\n", "\neighteen\n", 1),
+			expected: "--- from\n+++ to\n" +
+				"@@ -1,5 +1,5 @@\n 1\n-2\n+two\n 3\n 4\n 5\n" +
+				"@@ -15,6 +15,6 @@\n 15\n 16\n 17\n-18\n+eighteen\n 19\n 20\n",
+		},
+		{
+			name:     "changes close together in one hunk",
+			fromText: lines(1, 10),
+			toText:   strings.Replace(strings.Replace(lines(1, 10), "\n3\n", "\nthree\n", 1), "\n8\n", "\neight\n", 1),
+			expected: "--- from\n+++ to\n" +
+				"@@ -1,10 +1,10 @@\n 1\n 2\n-3\n+three\n 4\n 5\n 6\n 7\n-8\n+eight\n 9\n 10\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := UnifiedDiff("from", "to", test.fromText, test.toText)
+			if actual != test.expected {
+				t.Errorf("expected:\n%s\nactual:\n%s", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiffOfLargeChangedBlock(t *testing.T) {
+	fromLines, toLines := []string{"header"}, []string{"header"}
+	for i := 0; i < 3000; i++ {
+		fromLines = append(fromLines, fmt.Sprintf("from %d", i))
+		toLines = append(toLines, fmt.Sprintf("to %d", i))
+	}
+	fromLines, toLines = append(fromLines, "footer"), append(toLines, "footer")
+
+	diff := UnifiedDiff("from", "to", strings.Join(fromLines, "\n"), strings.Join(toLines, "\n"))
+	expectedHeader := "--- from\n+++ to\n@@ -1,3002 +1,3002 @@\n header\n-from 0\n"
+	if !strings.HasPrefix(diff, expectedHeader) {
+		t.Fatalf("expected the diff to start with:\n%s\nactual:\n%s", expectedHeader, diff[:len(expectedHeader)])
+	}
+	if strings.Count(diff, "\n-from ") != 3000 || strings.Count(diff, "\n+to ") != 3000 {
+		t.Errorf("expected the changed block to be removed and added as a whole")
+	}
+	if !strings.HasSuffix(diff, "+to 2999\n footer\n\\ No newline at end of file\n") {
+		t.Errorf("expected the diff to end with the added block and the footer")
+	}
+}
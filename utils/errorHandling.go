@@ -5,45 +5,191 @@ import (
 	"fmt"
 	"github.com/apex/log"
 	"os"
+	"strconv"
 	"strings"
 )
 
-// Outputs a list of errors to stderr, and aborts the program if there are any errors
+// shared by all prompts, so that no buffered input gets lost between them
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// Outputs a list of errors to stderr, and aborts the program if there are any errors. The exit code is the
+// one of the class of the first classified error.
 func OutputAndAbortIfErrors(errorsList []error) {
 	log.Debug("utils.OutputAndAbortIfErrors()")
-	for _, err := range errorsList {
-		_, _ = fmt.Fprintln(os.Stderr, "Error: ", err)
-	}
-
-	if len(errorsList) > 0 {
-		os.Exit(1)
-	}
+	OutputAndAbortIfError(JoinErrors(errorsList))
 }
 
-// Outputs an error to stderr, if there is one, and aborts the program if so
+// Outputs an error to stderr, if there is one, and aborts the program with the exit code of its class if so
 func OutputAndAbortIfError(error error) {
 	log.Debug("utils.OutputAndAbortIfError()")
 	if error != nil {
-		_, _ = fmt.Fprintln(os.Stderr, "Error: ", error)
-		os.Exit(1)
+		OutputError(error)
+		setExitReason(ExitCode(error), error)
+		Exit(ExitCode(error))
+	}
+}
+
+// Outputs a new error of the given class and aborts the program with the exit code of the class
+func AbortWithError(errorClass *ErrorClassType, format string, args ...interface{}) {
+	OutputAndAbortIfError(NewError(errorClass, format, args...))
+}
+
+// Outputs an error to stderr, one line per error if several errors are joined
+func OutputError(error error) {
+	for _, line := range strings.Split(error.Error(), "\n") {
+		_, _ = fmt.Fprintln(os.Stderr, "Error: ", line)
 	}
 }
 
+// Set by the global --yes and --no-input flags
+var (
+	AssumeYesFlag bool
+	NoInputFlag   bool
+)
+
+// Setting this environment variable to 1, true, or yes has the same effect as --yes
+const AssumeYesEnvVar = "DIVEKIT_ASSUME_YES"
+
+// A confirmation that a command needs before it does something that can't be undone
+type ConfirmationType struct {
+	Action  string   // what will be done, e.g. "Create the repos of distribution milestone"
+	Details []string // what exactly is affected, e.g. group IDs and repo counts
+}
+
+// Returns true if all confirmations are given in advance, by --yes or DIVEKIT_ASSUME_YES
+func AssumeYes() bool {
+	if AssumeYesFlag {
+		return true
+	}
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(AssumeYesEnvVar))) {
+	case "1", "true", "yes":
+		return true
+	}
+	return false
+}
+
 // Asks the user to confirm an action, and aborts if the user doesn't confirm
 func Confirm(prompt string) {
-	reader := bufio.NewReader(os.Stdin)
+	RequireConfirmations(ConfirmationType{Action: prompt})
+}
 
-	fmt.Printf("%s\n\n(Please type \"yes\" to confirm, or anything else to abort):\n", prompt)
+// Asks the user to confirm all the given actions at once, and aborts if the user doesn't confirm. With --yes,
+// the actions are only logged. With --no-input, or if there is no input, the program aborts with a listing
+// of what would have had to be confirmed.
+func RequireConfirmations(confirmations ...ConfirmationType) {
+	if len(confirmations) == 0 {
+		return
+	}
+	listing := formatConfirmations(confirmations)
+	if AssumeYes() {
+		log.Warn("Confirmed by --yes / " + AssumeYesEnvVar + ":\n" + listing)
+		return
+	}
+	if NoInputFlag {
+		abortNonInteractive("These actions need a confirmation, but --no-input is set", listing)
+	}
+	fmt.Printf("%s\n(Please type \"yes\" to confirm, or anything else to abort):\n", listing)
 
-	input, err := reader.ReadString('\n')
-	if err != nil {
-		fmt.Printf("Error reading input: %v\n", err)
-		os.Exit(1)
+	input, err := readInputLine()
+	if err != nil && input == "" {
+		abortNonInteractive(fmt.Sprintf("These actions need a confirmation, but no input could be read (%v)", err),
+			listing)
 	}
 
 	input = strings.TrimSpace(strings.ToLower(input))
 	if input != "yes" {
 		fmt.Println("Aborting")
-		os.Exit(1)
+		Exit(ErrAborted.ExitCode)
+	}
+}
+
+func formatConfirmations(confirmations []ConfirmationType) string {
+	builder := &strings.Builder{}
+	for _, confirmation := range confirmations {
+		builder.WriteString(confirmation.Action + "\n")
+		for _, detail := range confirmation.Details {
+			builder.WriteString("    " + detail + "\n")
+		}
+	}
+	return builder.String()
+}
+
+func abortNonInteractive(reason, listing string) {
+	_, _ = fmt.Fprintf(os.Stderr, "%s:\n%s\nRun again with --yes (or %s=1) to confirm them.\n",
+		reason, listing, AssumeYesEnvVar)
+	Exit(ErrAborted.ExitCode)
+}
+
+// Asks the user to pick one or several of the given options, and aborts if the input is invalid.
+// The user can enter comma-separated numbers, or "all". Returns the chosen options.
+func Choose(prompt string, options []string) []string {
+	if NoInputFlag {
+		_, _ = fmt.Fprintf(os.Stderr, "%s\n  %s\nA choice is needed, but --no-input is set.\n",
+			prompt, strings.Join(options, "\n  "))
+		Exit(ErrAborted.ExitCode)
+	}
+	fmt.Printf("%s\n", prompt)
+	for index, option := range options {
+		fmt.Printf("  [%d] %s\n", index+1, option)
+	}
+	fmt.Printf("\n(Please type the number(s) of your choice, separated by commas, or \"all\"):\n")
+
+	input, err := readInputLine()
+	if err != nil {
+		fmt.Printf("Error reading input: %v\n", err)
+		Exit(ErrAborted.ExitCode)
+	}
+
+	input = strings.TrimSpace(strings.ToLower(input))
+	if input == "all" {
+		return options
+	}
+	chosen := []string{}
+	for _, numberString := range strings.Split(input, ",") {
+		number, err := strconv.Atoi(strings.TrimSpace(numberString))
+		if err != nil || number < 1 || number > len(options) {
+			fmt.Printf("Invalid choice: %s\nAborting\n", numberString)
+			Exit(ErrAborted.ExitCode)
+		}
+		chosen = append(chosen, options[number-1])
+	}
+	return chosen
+}
+
+// Asks the user for a value, and returns the default value if the user just presses enter. With --no-input,
+// the default value is used without asking.
+func Prompt(prompt string, defaultValue string) string {
+	if NoInputFlag {
+		log.Info(fmt.Sprintf("%s: using the default '%s' (--no-input)", prompt, defaultValue))
+		return defaultValue
+	}
+	if defaultValue != "" {
+		fmt.Printf("%s [%s]: ", prompt, defaultValue)
+	} else {
+		fmt.Printf("%s: ", prompt)
+	}
+
+	input, err := readInputLine()
+	if err != nil && input == "" {
+		fmt.Printf("Error reading input: %v\n", err)
+		Exit(ErrAborted.ExitCode)
+	}
+
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return defaultValue
+	}
+	return input
+}
+
+// Same as Prompt, but for a number. Asks again if the input is not a number.
+func PromptInt(prompt string, defaultValue int) int {
+	for {
+		input := Prompt(prompt, strconv.Itoa(defaultValue))
+		number, err := strconv.Atoi(input)
+		if err == nil {
+			return number
+		}
+		fmt.Printf("'%s' is not a number, please try again.\n", input)
 	}
 }
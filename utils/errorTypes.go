@@ -0,0 +1,113 @@
+package utils
+
+/**
+ * This file contains the error classes of the CLI. The packages return errors of these classes instead of
+ * aborting the program, and the commands translate them into messages and exit codes. Each class has its own
+ * exit code, so that wrapper scripts can react to the kind of problem.
+ */
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// A class of errors, with the exit code of the program if such an error aborts it
+type ErrorClassType struct {
+	Name        string
+	ExitCode    int
+	Description string
+}
+
+func (errorClass *ErrorClassType) Error() string {
+	return errorClass.Name
+}
+
+var (
+	ErrGeneral       = &ErrorClassType{"error", 1, "any other error"}
+	ErrUsage         = &ErrorClassType{"usage", 2, "invalid command line: unknown command or flag, missing argument"}
+	ErrNotFound      = &ErrorClassType{"not found", 3, "a directory, file, executable, or distribution does not exist"}
+	ErrInvalidConfig = &ErrorClassType{"invalid config", 4, "a config file can't be read or is not valid"}
+	ErrAmbiguous     = &ErrorClassType{"ambiguous", 5, "several files match where exactly one is expected"}
+	ErrAborted       = &ErrorClassType{"aborted", 6, "a confirmation was refused, or is needed but no input is allowed"}
+	ErrBlocked       = &ErrorClassType{"blocked", 7, "a safety rule with severity block is violated"}
+	ErrToolFailed    = &ErrorClassType{"tool failed", 8, "the ARS or the Repo Editor failed"}
+	ErrLocked        = &ErrorClassType{"locked", 9, "another run holds the run lock, or left an unrestored snapshot"}
+	ErrTimeout       = &ErrorClassType{"timeout", 10, "a phase or a tool didn't finish within its timeout"}
+	ErrIO            = &ErrorClassType{"i/o", 11, "a file or directory can't be written or copied"}
+	ErrInterrupted   = &ErrorClassType{"interrupted", 130, "the run was interrupted, e.g. by Ctrl-C"}
+)
+
+// All error classes, ordered by exit code
+var ErrorClasses = []*ErrorClassType{
+	ErrGeneral, ErrUsage, ErrNotFound, ErrInvalidConfig, ErrAmbiguous, ErrAborted, ErrBlocked, ErrToolFailed,
+	ErrLocked, ErrTimeout, ErrIO, ErrInterrupted,
+}
+
+// An error that belongs to an error class. errors.Is works for the class as well as for the wrapped error.
+type ClassifiedError struct {
+	Class *ErrorClassType
+	Err   error
+}
+
+func (classifiedError *ClassifiedError) Error() string {
+	return classifiedError.Err.Error()
+}
+
+func (classifiedError *ClassifiedError) Unwrap() []error {
+	return []error{classifiedError.Class, classifiedError.Err}
+}
+
+// Returns a new error of the given class, formatted like fmt.Errorf (so %w can be used)
+func NewError(errorClass *ErrorClassType, format string, args ...interface{}) error {
+	return &ClassifiedError{Class: errorClass, Err: fmt.Errorf(format, args...)}
+}
+
+// Puts an error into a class, if it doesn't have one yet. Returns nil for nil.
+func ClassifyError(errorClass *ErrorClassType, err error) error {
+	if err == nil {
+		return nil
+	}
+	var classifiedError *ClassifiedError
+	if errors.As(err, &classifiedError) {
+		return err
+	}
+	return &ClassifiedError{Class: errorClass, Err: err}
+}
+
+// Returns a new error for a failure to read a file: of class ErrNotFound if the file doesn't exist,
+// unclassified otherwise
+func NewReadError(err error, format string, args ...interface{}) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewError(ErrNotFound, format, args...)
+	}
+	return fmt.Errorf(format, args...)
+}
+
+// Returns the class of an error, ErrGeneral if it has none
+func ErrorClassOf(err error) *ErrorClassType {
+	var classifiedError *ClassifiedError
+	if errors.As(err, &classifiedError) {
+		return classifiedError.Class
+	}
+	return ErrGeneral
+}
+
+// Returns the exit code for an error, 0 for nil
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	return ErrorClassOf(err).ExitCode
+}
+
+// Combines a list of errors into one, nil if the list is empty. The class is the one of the first classified error.
+func JoinErrors(errorsList []error) error {
+	if len(errorsList) == 0 {
+		return nil
+	}
+	if len(errorsList) == 1 {
+		return errorsList[0]
+	}
+	return errors.Join(errorsList...)
+}
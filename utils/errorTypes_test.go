@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"no error", nil, 0},
+		{"unclassified error", errors.New("failed"), 1},
+		{"usage", NewError(ErrUsage, "unknown flag"), 2},
+		{"not found", NewError(ErrNotFound, "no such distribution"), 3},
+		{"invalid config", NewError(ErrInvalidConfig, "invalid JSON"), 4},
+		{"ambiguous", NewError(ErrAmbiguous, "several files"), 5},
+		{"aborted", NewError(ErrAborted, "not confirmed"), 6},
+		{"blocked", NewError(ErrBlocked, "policy violated"), 7},
+		{"tool failed", NewError(ErrToolFailed, "npm start failed"), 8},
+		{"locked", NewError(ErrLocked, "lock held"), 9},
+		{"timeout", NewError(ErrTimeout, "too slow"), 10},
+		{"i/o", NewError(ErrIO, "can't write"), 11},
+		{"interrupted", NewError(ErrInterrupted, "Ctrl-C"), 130},
+		{"wrapped with %w", fmt.Errorf("Error patching: %w", NewError(ErrToolFailed, "failed")), 8},
+		{"wrapped with %v", fmt.Errorf("Error patching: %v", NewError(ErrToolFailed, "failed")), 1},
+		{"classified", ClassifyError(ErrIO, errors.New("disk full")), 11},
+		{"classified twice", ClassifyError(ErrIO, NewError(ErrTimeout, "too slow")), 10},
+		{"read error of a missing file", NewReadError(fs.ErrNotExist, "no file"), 3},
+		{"read error of another kind", NewReadError(fs.ErrPermission, "no access"), 1},
+		{"joined", JoinErrors([]error{errors.New("first"), NewError(ErrBlocked, "second")}), 7},
+		{"joined single", JoinErrors([]error{NewError(ErrAborted, "only")}), 6},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := ExitCode(test.err); actual != test.expected {
+				t.Errorf("expected exit code %d, got %d", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestErrorClassesHaveDistinctExitCodes(t *testing.T) {
+	exitCodes := map[int]string{}
+	for _, errorClass := range ErrorClasses {
+		if otherName, found := exitCodes[errorClass.ExitCode]; found {
+			t.Errorf("classes %s and %s have the same exit code %d", otherName, errorClass.Name, errorClass.ExitCode)
+		}
+		exitCodes[errorClass.ExitCode] = errorClass.Name
+	}
+}
+
+func TestClassifiedErrorKeepsWrappedError(t *testing.T) {
+	err := ClassifyError(ErrIO, fmt.Errorf("copying: %w", fs.ErrPermission))
+	if !errors.Is(err, ErrIO) {
+		t.Errorf("expected the error to be of class %s", ErrIO.Name)
+	}
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("expected the wrapped error to be kept")
+	}
+	if err.Error() != "copying: permission denied" {
+		t.Errorf("unexpected message: %s", err.Error())
+	}
+	if ClassifyError(ErrIO, nil) != nil {
+		t.Errorf("expected nil for nil")
+	}
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/apex/log"
@@ -8,8 +10,11 @@ import (
 	"io/fs"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 )
 
 // Searches recursively for full path(es) of a given filename. Returns a 1-elem
@@ -62,13 +67,17 @@ func TransformIntoRelativePaths(root string, absPath string) (string, error) {
 }
 
 func CopyFile(srcFileName, destDirName string) error {
+	return CopyFileTo(srcFileName, filepath.Join(destDirName, filepath.Base(srcFileName)))
+}
+
+// Same as CopyFile, but with the full path of the destination file instead of its directory
+func CopyFileTo(srcFileName, destFullPath string) error {
 	srcFile, err := os.Open(srcFileName)
 	if err != nil {
 		return err
 	}
 	defer srcFile.Close()
 
-	destFullPath := filepath.Join(destDirName, filepath.Base(srcFileName))
 	destFile, err := os.Create(destFullPath)
 	if err != nil {
 		return err
@@ -140,7 +149,7 @@ func ValidatePath(shouldBeDir bool, path string) error {
 	log.Debug("utils.ValidatePath()")
 	fileInfo, err := os.Stat(path)
 	if os.IsNotExist(err) {
-		return fmt.Errorf("%s does not exist", path)
+		return NewError(ErrNotFound, "%s does not exist", path)
 	}
 	if err != nil {
 		return fmt.Errorf("Error checking for %s", err)
@@ -152,7 +161,7 @@ func ValidatePath(shouldBeDir bool, path string) error {
 		} else {
 			errorMessage += "directory, not a file"
 		}
-		return fmt.Errorf(errorMessage, path)
+		return NewError(ErrNotFound, errorMessage, path)
 	}
 
 	return nil
@@ -184,27 +193,70 @@ func ValidateAllPaths(shouldBeDir bool, paths ...string) []error {
 }
 
 func FindUniqueFileWithPrefix(dir, prefix string) (string, error) {
+	matchingFiles, err := FindFilesWithPrefix(dir, prefix)
+	if err != nil {
+		return "", err
+	}
+
+	if len(matchingFiles) == 0 {
+		return "", NewError(ErrNotFound, "No file found with prefix '%s' in directory '%s'", prefix, dir)
+	}
+
+	if len(matchingFiles) > 1 {
+		return "", NewError(ErrAmbiguous, "Multiple files found with prefix '%s' in directory '%s'", prefix, dir)
+	}
+
+	return matchingFiles[0], nil
+}
+
+// Lists the full paths of all files (not directories) in dir whose name starts with prefix
+func FindFilesWithPrefix(dir, prefix string) ([]string, error) {
 	files, err := ioutil.ReadDir(dir)
 	if err != nil {
-		return "", fmt.Errorf("Error reading directory: %v", err)
+		return nil, fmt.Errorf("Error reading directory: %v", err)
 	}
 
 	matchingFiles := []string{}
 	for _, file := range files {
 		if !file.IsDir() && strings.HasPrefix(file.Name(), prefix) {
-			matchingFiles = append(matchingFiles, file.Name())
+			matchingFiles = append(matchingFiles, filepath.Join(dir, file.Name()))
 		}
 	}
+	return matchingFiles, nil
+}
 
-	if len(matchingFiles) == 0 {
-		return "", fmt.Errorf("No file found with prefix '%s' in directory '%s'", prefix, dir)
+// Returns the modification times of all files (not directories) directly in dir, keyed by file name.
+// Used to find out afterwards which files an external tool has created or changed.
+func ListFileModTimes(dir string) (map[string]time.Time, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading directory: %v", err)
 	}
-
-	if len(matchingFiles) > 1 {
-		return "", fmt.Errorf("Multiple files found with prefix '%s' in directory '%s'", prefix, dir)
+	modTimes := make(map[string]time.Time)
+	for _, file := range files {
+		if !file.IsDir() {
+			modTimes[file.Name()] = file.ModTime()
+		}
 	}
+	return modTimes, nil
+}
 
-	return filepath.Join(dir, matchingFiles[0]), nil
+// Returns the full paths of all files in dir that are new or have changed compared to
+// a snapshot taken earlier with ListFileModTimes
+func FindNewOrChangedFiles(dir string, before map[string]time.Time) ([]string, error) {
+	after, err := ListFileModTimes(dir)
+	if err != nil {
+		return nil, err
+	}
+	changedFiles := []string{}
+	for fileName, modTime := range after {
+		previousModTime, existed := before[fileName]
+		if !existed || !previousModTime.Equal(modTime) {
+			changedFiles = append(changedFiles, filepath.Join(dir, fileName))
+		}
+	}
+	sort.Strings(changedFiles)
+	return changedFiles, nil
 }
 
 func ListSubfolderNames(folderPath string) ([]string, error) {
@@ -237,3 +289,52 @@ func DeepCopy(srcObject, destinationObject interface{}) error {
 
 	return nil
 }
+
+// Checks if a slash-separated path matches a glob pattern. Besides the usual wildcards of path.Match,
+// the pattern may contain "**" as a path segment, which matches zero or more directories. A pattern with a
+// trailing "/" matches the directory and everything below it, like the same pattern followed by "**".
+func MatchDoublestar(pattern, slashPath string) (bool, error) {
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(slashPath, "/"))
+}
+
+func matchSegments(patternSegments, pathSegments []string) (bool, error) {
+	for len(patternSegments) > 0 {
+		if patternSegments[0] == "**" {
+			for skipped := 0; skipped <= len(pathSegments); skipped++ {
+				matched, err := matchSegments(patternSegments[1:], pathSegments[skipped:])
+				if err != nil || matched {
+					return matched, err
+				}
+			}
+			return false, nil
+		}
+		if len(pathSegments) == 0 {
+			return false, nil
+		}
+		matched, err := path.Match(patternSegments[0], pathSegments[0])
+		if err != nil || !matched {
+			return false, err
+		}
+		patternSegments = patternSegments[1:]
+		pathSegments = pathSegments[1:]
+	}
+	return len(pathSegments) == 0, nil
+}
+
+// Checks if a string contains any of the glob wildcard characters
+func ContainsGlobMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// Returns the hex-encoded SHA-256 hash of a file's content
+func HashFile(filePath string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s for hashing: %v", filePath, err)
+	}
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:]), nil
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestMatchDoublestar(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		// without "**", like path.Match segment by segment
+		{"src/main/A.java", "src/main/A.java", true},
+		{"src/*/A.java", "src/main/A.java", true},
+		{"src/*/A.java", "src/main/java/A.java", false},
+		{"*.java", "src/A.java", false},
+
+		// "**" at the start
+		{"**/A.java", "A.java", true},
+		{"**/A.java", "src/main/java/A.java", true},
+		{"**/*_solution*", "src/main/java/Shop_solution.java", true},
+		{"**/*_solution*", "src/main/java/Shop.java", false},
+
+		// "**" in the middle, also for zero dirs
+		{"src/**/A.java", "src/A.java", true},
+		{"src/**/A.java", "src/main/java/A.java", true},
+		{"src/**/test/*.java", "src/test/A.java", true},
+		{"src/**/test/*.java", "src/main/test/A.java", true},
+		{"src/**/test/*.java", "src/main/test/b/A.java", false},
+		{"src/**/A.java", "test/src/A.java", false},
+
+		// "**" at the end
+		{"src/**", "src/main/java/A.java", true},
+		{"src/**", "src", true},
+		{"src/**", "srcs/A.java", false},
+		{"**/*_solution*/**", "src/shop_solution/A.java", true},
+		{"**/*_solution*/**", "src/shop/A.java", false},
+
+		// several "**"
+		{"**/main/**/*.java", "src/main/java/a/A.java", true},
+		{"**/main/**/*.java", "main/A.java", true},
+		{"**/main/**/*.java", "src/test/java/A.java", false},
+
+		// a trailing "/" matches everything below the dir
+		{"src/solution/", "src/solution/A.java", true},
+		{"src/solution/", "src/solution/a/b/A.java", true},
+		{"src/solution/", "src/solution", true},
+		{"src/solution/", "src/solutions/A.java", false},
+		{"**/solution/", "src/main/solution/A.java", true},
+
+		// no match
+		{"src/main/A.java", "src/main/B.java", false},
+		{"src/main", "src/main/A.java", false},
+		{"src/main/A.java", "src/main", false},
+		{"", "A.java", false},
+	}
+	for _, test := range tests {
+		matched, err := MatchDoublestar(test.pattern, test.path)
+		if err != nil {
+			t.Errorf("%s, %s: unexpected error: %v", test.pattern, test.path, err)
+			continue
+		}
+		if matched != test.expected {
+			t.Errorf("%s, %s: expected %v, got %v", test.pattern, test.path, test.expected, matched)
+		}
+	}
+}
+
+func TestMatchDoublestarWithInvalidPattern(t *testing.T) {
+	if _, err := MatchDoublestar("src/[a-/A.java", "src/a/A.java"); err == nil {
+		t.Errorf("expected an error for an invalid pattern")
+	}
+}
@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// A JSON value that keeps the order of its keys, if it is an object. All other values are kept as raw JSON.
+type orderedJSONValue struct {
+	keys     []string
+	fields   map[string]*orderedJSONValue
+	raw      json.RawMessage // nil for objects
+	source   json.RawMessage // the value as it was parsed
+	verbatim bool            // write source as it is, because the value hasn't changed
+}
+
+// Marshals content into the original JSON document, without losing anything the content doesn't know about:
+// the values of the keys in content replace the original ones, keys that are only in the original document
+// are kept, and the original order of the keys is preserved. New keys are appended to their object.
+// Values that haven't changed keep their original formatting, everything else is indented with two spaces.
+// If original is empty, this is the same as marshalling content with indentation.
+func MarshalJSONPreservingOriginal(original []byte, content interface{}) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(content); err != nil {
+		return nil, err
+	}
+	updated, err := parseOrderedJSON(buffer.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(original)) == 0 {
+		return formatOrderedJSON(updated), nil
+	}
+
+	originalValue, err := parseOrderedJSON(original)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the original JSON: %v", err)
+	}
+	merged := mergeOrderedJSON(originalValue, updated)
+	result := formatOrderedJSON(merged)
+	if equalJSON(original, result) {
+		return original, nil
+	}
+	return result, nil
+}
+
+func parseOrderedJSON(data []byte) (*orderedJSONValue, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '{' {
+		if !json.Valid(data) {
+			return nil, fmt.Errorf("invalid JSON value: %s", string(data))
+		}
+		return &orderedJSONValue{raw: json.RawMessage(data), source: json.RawMessage(data)}, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	value := &orderedJSONValue{fields: make(map[string]*orderedJSONValue), source: json.RawMessage(data)}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := token.(string)
+		var fieldData json.RawMessage
+		if err = decoder.Decode(&fieldData); err != nil {
+			return nil, err
+		}
+		field, err := parseOrderedJSON(fieldData)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := value.fields[key]; !exists {
+			value.keys = append(value.keys, key)
+		}
+		value.fields[key] = field
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Merges updated into original: objects are merged key by key, all other values are replaced. Values that
+// are equal are taken from the original, so that their formatting is kept.
+func mergeOrderedJSON(original, updated *orderedJSONValue) *orderedJSONValue {
+	if equalJSON(original.source, updated.source) {
+		return &orderedJSONValue{source: original.source, verbatim: true}
+	}
+	if original.raw != nil || updated.raw != nil {
+		return updated
+	}
+	merged := &orderedJSONValue{fields: make(map[string]*orderedJSONValue)}
+	for _, key := range original.keys {
+		merged.keys = append(merged.keys, key)
+		if updatedField, exists := updated.fields[key]; exists {
+			merged.fields[key] = mergeOrderedJSON(original.fields[key], updatedField)
+		} else {
+			merged.fields[key] = &orderedJSONValue{source: original.fields[key].source, verbatim: true}
+		}
+	}
+	for _, key := range updated.keys {
+		if _, exists := original.fields[key]; !exists {
+			merged.keys = append(merged.keys, key)
+			merged.fields[key] = updated.fields[key]
+		}
+	}
+	return merged
+}
+
+func formatOrderedJSON(value *orderedJSONValue) []byte {
+	buffer := &bytes.Buffer{}
+	writeOrderedJSON(buffer, value, "")
+	buffer.WriteString("\n")
+	return buffer.Bytes()
+}
+
+func writeOrderedJSON(buffer *bytes.Buffer, value *orderedJSONValue, indent string) {
+	if value.verbatim {
+		buffer.Write(value.source)
+		return
+	}
+	if value.raw != nil {
+		if err := json.Indent(buffer, value.raw, indent, "  "); err != nil {
+			buffer.Write(value.raw)
+		}
+		return
+	}
+	if len(value.keys) == 0 {
+		buffer.WriteString("{}")
+		return
+	}
+	buffer.WriteString("{\n")
+	for index, key := range value.keys {
+		encodedKey, _ := json.Marshal(key)
+		buffer.WriteString(indent + "  ")
+		buffer.Write(encodedKey)
+		buffer.WriteString(": ")
+		writeOrderedJSON(buffer, value.fields[key], indent+"  ")
+		if index < len(value.keys)-1 {
+			buffer.WriteString(",")
+		}
+		buffer.WriteString("\n")
+	}
+	buffer.WriteString(indent + "}")
+}
+
+// Checks if two JSON documents are the same, apart from whitespace
+func equalJSON(first, second []byte) bool {
+	firstBuffer, secondBuffer := &bytes.Buffer{}, &bytes.Buffer{}
+	if json.Compact(firstBuffer, first) != nil || json.Compact(secondBuffer, second) != nil {
+		return false
+	}
+	return bytes.Equal(firstBuffer.Bytes(), secondBuffer.Bytes())
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type testRemoteConfig struct {
+	GroupId int `json:"groupId"`
+}
+
+type testConfig struct {
+	Name     string           `json:"name"`
+	GroupIds []int            `json:"groupIds"`
+	Remote   testRemoteConfig `json:"remote"`
+}
+
+func TestMarshalJSONPreservingOriginal(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		content  testConfig
+		expected string
+	}{
+		{
+			name:     "no original",
+			original: "",
+			content:  testConfig{Name: "st2", GroupIds: []int{1, 2}, Remote: testRemoteConfig{GroupId: 3}},
+			expected: "{\n  \"name\": \"st2\",\n  \"groupIds\": [\n    1,\n    2\n  ],\n  \"remote\": {\n    \"groupId\": 3\n  }\n}\n",
+		},
+		{
+			name:     "unchanged document kept as it is",
+			original: "{ \"name\":\"st2\", \"groupIds\": [1,2],\n\"remote\": {\"groupId\": 3} }",
+			content:  testConfig{Name: "st2", GroupIds: []int{1, 2}, Remote: testRemoteConfig{GroupId: 3}},
+			expected: "{ \"name\":\"st2\", \"groupIds\": [1,2],\n\"remote\": {\"groupId\": 3} }",
+		},
+		{
+			name:     "unknown keys kept in their order",
+			original: "{\"comment\": \"keep me\", \"name\": \"old\", \"extra\": {\"a\": [1, 2]}, \"groupIds\": [1], \"remote\": {\"groupId\": 3}}",
+			content:  testConfig{Name: "new", GroupIds: []int{1}, Remote: testRemoteConfig{GroupId: 3}},
+			expected: "{\n  \"comment\": \"keep me\",\n  \"name\": \"new\",\n  \"extra\": {\"a\": [1, 2]},\n" +
+				"  \"groupIds\": [1],\n  \"remote\": {\"groupId\": 3}\n}\n",
+		},
+		{
+			name:     "nested object merged key by key",
+			original: "{\"name\": \"st2\", \"groupIds\": [], \"remote\": {\"token\": \"$TOKEN\", \"groupId\": 3}}",
+			content:  testConfig{Name: "st2", GroupIds: []int{}, Remote: testRemoteConfig{GroupId: 4}},
+			expected: "{\n  \"name\": \"st2\",\n  \"groupIds\": [],\n  \"remote\": {\n" +
+				"    \"token\": \"$TOKEN\",\n    \"groupId\": 4\n  }\n}\n",
+		},
+		{
+			name:     "duplicate keys collapsed, the last value replaced",
+			original: "{\"name\": \"first\", \"groupIds\": [1], \"name\": \"second\", \"remote\": {\"groupId\": 3}}",
+			content:  testConfig{Name: "third", GroupIds: []int{1}, Remote: testRemoteConfig{GroupId: 3}},
+			expected: "{\n  \"name\": \"third\",\n  \"groupIds\": [1],\n  \"remote\": {\"groupId\": 3}\n}\n",
+		},
+		{
+			name:     "duplicate keys collapsed, unchanged",
+			original: "{\"name\": \"first\", \"groupIds\": [1], \"name\": \"second\", \"remote\": {\"groupId\": 3}}",
+			content:  testConfig{Name: "second", GroupIds: []int{1}, Remote: testRemoteConfig{GroupId: 3}},
+			expected: "{\n  \"name\": \"second\",\n  \"groupIds\": [1],\n  \"remote\": {\"groupId\": 3}\n}\n",
+		},
+		{
+			name:     "changed array replaced as a whole",
+			original: "{\"name\": \"st2\", \"groupIds\": [1, 2], \"remote\": {\"groupId\": 3}}",
+			content:  testConfig{Name: "st2", GroupIds: []int{2}, Remote: testRemoteConfig{GroupId: 3}},
+			expected: "{\n  \"name\": \"st2\",\n  \"groupIds\": [\n    2\n  ],\n  \"remote\": {\"groupId\": 3}\n}\n",
+		},
+		{
+			name:     "missing keys appended",
+			original: "{\"comment\": \"keep me\", \"name\": \"st2\"}",
+			content:  testConfig{Name: "st2", GroupIds: []int{5}, Remote: testRemoteConfig{GroupId: 3}},
+			expected: "{\n  \"comment\": \"keep me\",\n  \"name\": \"st2\",\n  \"groupIds\": [\n    5\n  ],\n" +
+				"  \"remote\": {\n    \"groupId\": 3\n  }\n}\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := MarshalJSONPreservingOriginal([]byte(test.original), test.content)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(actual) != test.expected {
+				t.Errorf("expected:\n%s\nactual:\n%s", test.expected, string(actual))
+			}
+
+			// reading the result again gives the content
+			var roundTrip testConfig
+			if err := json.Unmarshal(actual, &roundTrip); err != nil {
+				t.Fatalf("the result is not valid JSON: %v", err)
+			}
+			if roundTrip.Name != test.content.Name || roundTrip.Remote != test.content.Remote ||
+				len(roundTrip.GroupIds) != len(test.content.GroupIds) {
+				t.Errorf("expected %+v after reading the result, got %+v", test.content, roundTrip)
+			}
+		})
+	}
+}
+
+func TestMarshalJSONPreservingOriginalWithInvalidOriginal(t *testing.T) {
+	_, err := MarshalJSONPreservingOriginal([]byte("{\"name\": "), testConfig{})
+	if err == nil {
+		t.Errorf("expected an error for invalid original JSON")
+	}
+}
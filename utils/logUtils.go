@@ -19,7 +19,11 @@ const (
 )
 
 var (
+	// the level of the messages that are output
 	LogLevel = log.InfoLevel
+	// functions that get every log entry that is output, and all warnings and errors even if they are not output,
+	// e.g. to collect the warnings for a report
+	logListeners []func(entry *log.Entry)
 )
 
 type CustomHandler struct {
@@ -31,14 +35,33 @@ func (h *CustomHandler) HandleLog(e *log.Entry) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	// Format the log message
-	msg := fmt.Sprintf("[%s] %s\n", cases.Title(language.English).String(fmt.Sprintf("%s", e.Level)), e.Message)
+	var err error
+	if e.Level >= LogLevel {
+		// Format the log message
+		msg := fmt.Sprintf("[%s] %s\n", cases.Title(language.English).String(fmt.Sprintf("%s", e.Level)), e.Message)
 
-	// Write the formatted message to the output writer
-	_, err := h.w.Write([]byte(msg))
+		// Write the formatted message to the output writer
+		_, err = h.w.Write([]byte(msg))
+	}
+	for _, listener := range logListeners {
+		listener(e)
+	}
+	if e.Level == log.FatalLevel {
+		setExitMessage(1, e.Message)
+		// log.Fatal exits right after this, so this is the last chance to clean up
+		h.mu.Unlock()
+		RunCleanups()
+		h.mu.Lock()
+	}
 	return err
 }
 
+// Registers a function that gets every log entry that is output, and all warnings and errors. It must not log
+// itself.
+func AddLogListener(listener func(entry *log.Entry)) {
+	logListeners = append(logListeners, listener)
+}
+
 func NewCustomHandler(w io.Writer) *CustomHandler {
 	return &CustomHandler{
 		w: w,
@@ -51,10 +74,19 @@ func DefineLoggingLevel(logLevelString string) error {
 	log.SetHandler(customHandler)
 	var err error = nil
 	LogLevel, err = StringAsLogLevel(logLevelString)
+	// warnings always reach the handler, which only outputs the messages of LogLevel and above
+	log.SetLevel(minLogLevel(LogLevel, log.WarnLevel))
 	log.Info("Log level set to " + LogLevelAsString() + ".")
 	return err
 }
 
+func minLogLevel(level, otherLevel log.Level) log.Level {
+	if level < otherLevel {
+		return level
+	}
+	return otherLevel
+}
+
 func LogLevelAsString() string {
 	switch LogLevel {
 	case log.DebugLevel:
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"github.com/apex/log"
+	"strings"
+	"testing"
+)
+
+func TestLogLevelOnlyFiltersTheOutput(t *testing.T) {
+	logger := log.Log.(*log.Logger)
+	previousLevel, previousLoggerLevel, previousHandler := LogLevel, logger.Level, logger.Handler
+	t.Cleanup(func() {
+		LogLevel, logger.Level, logger.Handler = previousLevel, previousLoggerLevel, previousHandler
+	})
+	output := &strings.Builder{}
+	log.SetHandler(NewCustomHandler(output))
+	LogLevel = log.ErrorLevel
+	log.SetLevel(minLogLevel(LogLevel, log.WarnLevel))
+
+	collecting := true
+	collected := []string{}
+	AddLogListener(func(entry *log.Entry) {
+		if collecting {
+			collected = append(collected, entry.Level.String()+": "+entry.Message)
+		}
+	})
+	log.Info("an info")
+	log.Warn("a warning")
+	log.Error("an error")
+	collecting = false
+
+	if output.String() != "[Error] an error\n" {
+		t.Errorf("expected only the error to be output, got:\n%s", output.String())
+	}
+	expected := "warn: a warning, error: an error"
+	if strings.Join(collected, ", ") != expected {
+		t.Errorf("expected the listener to get '%s', got '%s'", expected, strings.Join(collected, ", "))
+	}
+}
@@ -0,0 +1,219 @@
+package utils
+
+/**
+ * This file contains the context of a run: its phases (e.g. "generate" and "patch" of 'divekit patch'), the
+ * timeouts of the phases, and the handling of interrupts (Ctrl-C, SIGTERM). An interrupt during a phase
+ * cancels the context, which stops the running tool; the run then aborts at the next safe point, and the
+ * cleanups restore what the run has changed. A second interrupt exits right away.
+ */
+
+import (
+	"context"
+	"fmt"
+	"github.com/apex/log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// The name of the phase in which the cleanups run
+const CleanupPhase = "cleanup"
+
+// Global flags
+var (
+	PhaseTimeoutFlags []string // --timeout: "<duration>" for all phases, or "<phase>=<duration>"
+)
+
+var (
+	runContext, cancelRunContext = context.WithCancel(context.Background())
+
+	phaseMutex         sync.Mutex
+	currentPhase       string
+	phasesStarted      bool
+	phaseContext       = runContext
+	cancelPhaseContext = func() {}
+	phaseTimeouts      = map[string]time.Duration{} // "" is the timeout of all phases
+	phaseListeners     []func(phase string)
+	waitingForInput    bool
+	interruptSignal    os.Signal
+	interruptedPhase   string
+)
+
+// Parses the --timeout flags. Returns an error of class ErrUsage if a flag can't be parsed.
+func ParsePhaseTimeouts() error {
+	log.Debug("utils.ParsePhaseTimeouts()")
+	phaseMutex.Lock()
+	defer phaseMutex.Unlock()
+	for _, timeoutFlag := range PhaseTimeoutFlags {
+		phase, durationString := "", timeoutFlag
+		if index := strings.LastIndex(timeoutFlag, "="); index >= 0 {
+			phase, durationString = strings.TrimSpace(timeoutFlag[:index]), timeoutFlag[index+1:]
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(durationString))
+		if err != nil || duration <= 0 {
+			return NewError(ErrUsage, "invalid --timeout '%s' - expected a duration like '30m', or "+
+				"<phase>=<duration> like 'generate=30m'", timeoutFlag)
+		}
+		phaseTimeouts[phase] = duration
+	}
+	return nil
+}
+
+// Catches SIGINT and SIGTERM from now on, see handleInterrupt
+func HandleInterrupts() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		handleInterrupt(<-signals)
+		receivedSignal := <-signals
+		log.Warn(fmt.Sprintf("Received %s again - exiting without waiting any longer", signalName(receivedSignal)))
+		exitInterrupted()
+	}()
+}
+
+// Handles the first interrupt. During a phase, the run context is cancelled: a running tool is stopped (see
+// ToolRunner), and the run aborts with an error of class ErrInterrupted at the start of the next phase or tool.
+// Outside of phases, or while waiting for input, nothing is in progress, so the program exits right away.
+func handleInterrupt(receivedSignal os.Signal) {
+	phaseMutex.Lock()
+	interruptSignal = receivedSignal
+	interruptedPhase = currentPhase
+	phase := currentPhase
+	graceful := phasesStarted && !waitingForInput && phase != CleanupPhase
+	phaseMutex.Unlock()
+	cancelRunContext()
+	if phase == CleanupPhase {
+		// exits as soon as the cleanups are done
+		log.Warn(fmt.Sprintf("Received %s while cleaning up - exiting when the cleanup is done",
+			signalName(receivedSignal)))
+		exitInterrupted()
+	}
+	if !graceful {
+		fmt.Println()
+		exitInterrupted()
+	}
+	log.Warn(fmt.Sprintf("Received %s during phase '%s' - stopping the run and cleaning up. "+
+		"Send it again to exit right away.", signalName(receivedSignal), phase))
+}
+
+func exitInterrupted() {
+	OutputAndAbortIfError(InterruptedError())
+}
+
+// Returns the error of class ErrInterrupted that describes the interrupt, or nil if the run isn't interrupted
+func InterruptedError() error {
+	phaseMutex.Lock()
+	defer phaseMutex.Unlock()
+	if interruptSignal == nil {
+		return nil
+	}
+	if interruptedPhase == "" {
+		return NewError(ErrInterrupted, "interrupted by %s", signalName(interruptSignal))
+	}
+	return NewError(ErrInterrupted, "interrupted by %s during phase '%s'", signalName(interruptSignal),
+		interruptedPhase)
+}
+
+// e.g. "Ctrl-C (SIGINT)"
+func signalName(receivedSignal os.Signal) string {
+	switch receivedSignal {
+	case os.Interrupt:
+		return "Ctrl-C (SIGINT)"
+	case syscall.SIGTERM:
+		return "SIGTERM"
+	}
+	return receivedSignal.String()
+}
+
+// Returns the phase in which the run was interrupted, "" if it wasn't interrupted or not during a phase
+func InterruptedPhase() string {
+	phaseMutex.Lock()
+	defer phaseMutex.Unlock()
+	return interruptedPhase
+}
+
+// Returns the signal by which the run was interrupted, nil if it wasn't interrupted
+func InterruptSignal() os.Signal {
+	phaseMutex.Lock()
+	defer phaseMutex.Unlock()
+	return interruptSignal
+}
+
+// Starts the next phase of the run, with its timeout from --timeout. Returns an error of class ErrInterrupted
+// if the run has been interrupted in the meantime.
+func StartPhase(phase string) error {
+	log.Debug("utils.StartPhase() - phase: " + phase)
+	if err := InterruptedError(); err != nil {
+		return err
+	}
+	setPhase(phase)
+	return nil
+}
+
+func setPhase(phase string) {
+	phaseMutex.Lock()
+	cancelPhaseContext()
+	currentPhase = phase
+	phasesStarted = true
+	timeout, found := phaseTimeouts[phase]
+	if !found {
+		timeout = phaseTimeouts[""]
+	}
+	if timeout > 0 && phase != CleanupPhase {
+		phaseContext, cancelPhaseContext = context.WithTimeout(runContext, timeout)
+	} else {
+		phaseContext, cancelPhaseContext = context.WithCancel(runContext)
+	}
+	listeners := phaseListeners
+	phaseMutex.Unlock()
+	for _, listener := range listeners {
+		listener(phase)
+	}
+}
+
+// Returns the current phase, "" if no phase has been started
+func CurrentPhase() string {
+	phaseMutex.Lock()
+	defer phaseMutex.Unlock()
+	return currentPhase
+}
+
+// Returns the context of the current phase, which is done when the run is interrupted or the phase times out
+func PhaseContext() context.Context {
+	phaseMutex.Lock()
+	defer phaseMutex.Unlock()
+	return phaseContext
+}
+
+// Returns the timeout of the current phase, 0 if there is none
+func PhaseTimeout() time.Duration {
+	phaseMutex.Lock()
+	defer phaseMutex.Unlock()
+	if timeout, found := phaseTimeouts[currentPhase]; found {
+		return timeout
+	}
+	return phaseTimeouts[""]
+}
+
+// Registers a function that is called with the name of each phase that starts, e.g. for a report
+func AddPhaseListener(listener func(phase string)) {
+	phaseMutex.Lock()
+	defer phaseMutex.Unlock()
+	phaseListeners = append(phaseListeners, listener)
+}
+
+// Reads a line from stdin. An interrupt while waiting for it exits right away.
+func readInputLine() (string, error) {
+	phaseMutex.Lock()
+	waitingForInput = true
+	phaseMutex.Unlock()
+	defer func() {
+		phaseMutex.Lock()
+		waitingForInput = false
+		phaseMutex.Unlock()
+	}()
+	return stdinReader.ReadString('\n')
+}
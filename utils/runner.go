@@ -5,9 +5,13 @@ package utils
  */
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/apex/log"
-	"os"
+	"io"
 	"os/exec"
+	"strings"
 )
 
 // Global flags
@@ -15,31 +19,110 @@ var (
 	DryRunFlag bool // If true, then don't actually run the commands, just output what would be run
 )
 
-func RunNPMStartAlways(dirPath, infoMsg string) error {
-	return runNPMStartWithDryRunCheck(dirPath, infoMsg, false)
+// The names of the tools, used to prefix their output
+const (
+	ToolARS        = "ARS"
+	ToolRepoEditor = "Repo Editor"
+)
+
+// Runs a tool, also in a dry run
+func RunToolAlways(toolName, dirPath, infoMsg string) error {
+	return runToolWithDryRunCheck(toolName, dirPath, infoMsg, false, nil)
+}
+
+func RunTool(toolName, dirPath, infoMsg string) error {
+	return runToolWithDryRunCheck(toolName, dirPath, infoMsg, true, nil)
 }
 
-func RunNPMStart(dirPath, infoMsg string) error {
-	return runNPMStartWithDryRunCheck(dirPath, infoMsg, true)
+// Same as RunTool, but also writes the output of the tool to outputCopy, e.g. to evaluate it afterwards
+func RunToolWithOutputCopy(toolName, dirPath, infoMsg string, outputCopy io.Writer) error {
+	return runToolWithDryRunCheck(toolName, dirPath, infoMsg, true, outputCopy)
 }
 
-func runNPMStartWithDryRunCheck(dirPath, infoMsg string, skipIfDryRun bool) error {
-	log.Debug("utils.RunNPMStartInDir(): dirPath = " + dirPath)
-	log.Info(infoMsg + " by running 'npm start' in " + dirPath + ".")
+// Runs a tool in its repo dir, as configured in ToolConfigs (default: 'npm start'). Its output is captured line
+// by line and logged with the name of the tool (see toolOutput.go). If it fails, the error contains a summary
+// of the known error patterns in the output.
+func runToolWithDryRunCheck(toolName, dirPath, infoMsg string, skipIfDryRun bool, outputCopy io.Writer) error {
+	log.Debug("utils.runToolWithDryRunCheck(): dirPath = " + dirPath)
+	invocation, err := NewToolInvocation(toolName, dirPath)
+	if err != nil {
+		return err
+	}
+	log.Info(infoMsg + " by running '" + invocation.CommandLine() + "' in " + invocation.Dir + ".")
 	if skipIfDryRun && DryRunFlag {
-		log.Info("'Dry Run' flag set, therefore SKIP RUNNING 'npm start'.")
+		log.Info("'Dry Run' flag set, therefore SKIP RUNNING '" + invocation.CommandLine() + "'.")
 		return nil
 	}
 
-	// Run "npm start"
-	cmd := exec.Command("npm", "start")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Dir = dirPath
-	err := cmd.Run()
+	// stopped on an interrupt, at the timeout of the phase, or at the timeout of the tool
+	phaseCtx := PhaseContext()
+	ctx := phaseCtx
+	if invocation.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, invocation.Timeout)
+		defer cancel()
+	}
+	toolOutput := newToolOutput(toolName, outputCopy)
+	stdout, stderr := toolOutput.newLineWriter("stdout"), toolOutput.newLineWriter("stderr")
+	writeToolLogLine(toolName, "divekit", invocation.CommandLine()+" in "+invocation.Dir)
+	err = DefaultToolRunner.Run(ctx, invocation, stdout, stderr)
+	stdout.flush()
+	stderr.flush()
+	writeToolLogLine(toolName, "divekit", invocation.CommandLine()+" finished: "+exitStatus(err))
+
+	summary := toolOutput.errorSummary()
 	if err != nil {
-		log.Fatalf("Error running 'npm start': %v", err)
+		return toolRunError(invocation, err, phaseCtx, ctx, summary)
+	}
+	if summary != "" {
+		log.Warn(summary)
+	}
+	return nil
+}
+
+// The error of a failed tool run, with the reason why it was stopped, if it was
+func toolRunError(invocation *ToolInvocationType, err error, phaseCtx, toolCtx context.Context,
+	summary string) error {
+	errorClass := ErrToolFailed
+	message := fmt.Sprintf("running '%s' of the %s in %s failed: %v", invocation.CommandLine(),
+		invocation.ToolName, invocation.Dir, err)
+	switch {
+	case InterruptSignal() != nil:
+		errorClass = ErrInterrupted
+		message = fmt.Sprintf("%v - the %s was stopped", InterruptedError(), invocation.ToolName)
+	case errors.Is(phaseCtx.Err(), context.DeadlineExceeded):
+		errorClass = ErrTimeout
+		message = fmt.Sprintf("the phase '%s' didn't finish within its timeout of %v (--timeout) - the %s was "+
+			"stopped", CurrentPhase(), PhaseTimeout(), invocation.ToolName)
+	case errors.Is(toolCtx.Err(), context.DeadlineExceeded):
+		errorClass = ErrTimeout
+		message = fmt.Sprintf("the %s didn't finish within its timeout of %v (CLI settings) and was stopped",
+			invocation.ToolName, invocation.Timeout)
+	}
+	if summary != "" {
+		message += "\n" + summary
 	}
+	if logFilePath := ToolLogFilePath(); logFilePath != "" {
+		message += "\nThe full output is in " + logFilePath
+	}
+	return NewError(errorClass, "%s", message)
+}
 
-	return err
+func exitStatus(err error) string {
+	if err == nil {
+		return "exit status 0"
+	}
+	return err.Error()
+}
+
+// Returns the commit hash of the current HEAD of a git repo
+func GitHeadCommit(dirPath string) (string, error) {
+	log.Debug("utils.GitHeadCommit(): dirPath = " + dirPath)
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = dirPath
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("could not determine git commit of %s: %v", dirPath, err)
+	}
+	return strings.TrimSpace(string(output)), nil
 }
@@ -0,0 +1,266 @@
+package utils
+
+/**
+ * This file contains the handling of the output of the tools the CLI runs (ARS, Repo Editor): each line is
+ * logged with the name of the tool, at a level that fits the line, and written to the tool log file of the
+ * run. Known error patterns are collected, so that a failure can be explained in a few lines.
+ */
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/apex/log"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A known pattern in the output of a tool, with a hint what it means
+type ToolErrorPatternType struct {
+	Name   string
+	Regexp *regexp.Regexp
+	Hint   string
+}
+
+// The HTTP status codes are only matched in an HTTP context (e.g. "status code 404", "HTTP 404", "404 Not Found"),
+// as the output also contains plain numbers like project IDs and counts
+var ToolErrorPatterns = []*ToolErrorPatternType{
+	{"gitlab-unauthorized", httpStatusRegexp("401", "unauthorized", `\bunauthorized\b`),
+		"GitLab rejected the access token (401) - check the token configured for the tool"},
+	{"gitlab-forbidden", httpStatusRegexp("403", "forbidden", `\bforbidden\b`),
+		"GitLab denied access (403) - check that the token's user may access the groups and projects"},
+	{"gitlab-not-found", httpStatusRegexp("404", "not found", ""),
+		"GitLab didn't find a project or group (404) - check the IDs in repositoryConfig.json"},
+	{"gitlab-rate-limit", httpStatusRegexp("429", "too many requests", `rate.?limit|too many requests`),
+		"GitLab's rate limit was hit (429) - lower general.maxConcurrentWorkers, or try again later"},
+	{"missing-variable", regexp.MustCompile(
+		`(?i)(variable|placeholder)\S*\s.*(not found|not defined|undefined|missing|no value)|undefined variable`),
+		"a variable used in the origin repo has no value - check the variation and individualization configs"},
+	{"missing-node-module", regexp.MustCompile(`(?i)cannot find module|module not found`),
+		"a node module is missing - run 'npm install' in the tool repo"},
+}
+
+// Returns a case-insensitive regexp for an HTTP status code: after "status", "status code", "code" or "HTTP"
+// (e.g. "Request failed with status code 404", "HTTP/1.1 404"), or followed by its reason phrase, possibly
+// with a word in between (e.g. "404 Not Found", "404 Project Not Found", "404 (Not Found)"). other is an
+// alternative regexp, "" for none.
+func httpStatusRegexp(code, reason, other string) *regexp.Regexp {
+	pattern := `(?i)\b(?:status(?:\s*code)?|code|http(?:/[\d.]+)?)\W{0,2}` + code + `\b|\b` + code +
+		`\W{1,2}(?:\w+\s+)?` + strings.ReplaceAll(reason, " ", `\s+`)
+	if other != "" {
+		pattern += "|" + other
+	}
+	return regexp.MustCompile(pattern)
+}
+
+// how many lines are kept as examples of a pattern
+const maxToolErrorExamples = 3
+
+type toolErrorMatchType struct {
+	Pattern  *ToolErrorPatternType
+	Count    int
+	Examples []string
+}
+
+// The output of one run of a tool
+type toolOutputType struct {
+	toolName   string
+	outputCopy io.Writer
+	mutex      sync.Mutex
+	matches    []*toolErrorMatchType
+}
+
+var (
+	toolLogMutex    sync.Mutex
+	toolLogFile     *os.File
+	ansiEscapeCodes = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+	errorLineRegexp = regexp.MustCompile(`(?i)\b(error|err!|fatal|exception)\b`)
+	warnLineRegexp  = regexp.MustCompile(`(?i)\b(warn|warning)\b`)
+	debugLineRegexp = regexp.MustCompile(`(?i)^\W*(debug|verbose|silly)\b`)
+)
+
+// Opens the file to which the full output of all tools of this run is written, in addition to the log
+func OpenToolLogFile(filePath string) error {
+	log.Debug("utils.OpenToolLogFile() - filePath: " + filePath)
+	toolLogMutex.Lock()
+	defer toolLogMutex.Unlock()
+	err := os.MkdirAll(filepath.Dir(filePath), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create the dir of the tool log file: %v", err)
+	}
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open the tool log file: %v", err)
+	}
+	toolLogFile = file
+	return nil
+}
+
+// Returns the path of the tool log file, or "" if there is none
+func ToolLogFilePath() string {
+	toolLogMutex.Lock()
+	defer toolLogMutex.Unlock()
+	if toolLogFile == nil {
+		return ""
+	}
+	return toolLogFile.Name()
+}
+
+func CloseToolLogFile() {
+	toolLogMutex.Lock()
+	defer toolLogMutex.Unlock()
+	if toolLogFile != nil {
+		toolLogFile.Close()
+		toolLogFile = nil
+	}
+}
+
+func writeToolLogLine(toolName, stream, line string) {
+	toolLogMutex.Lock()
+	defer toolLogMutex.Unlock()
+	if toolLogFile != nil {
+		fmt.Fprintf(toolLogFile, "%s [%s] [%s] %s\n", time.Now().Format("2006-01-02T15:04:05.000"), toolName,
+			stream, line)
+	}
+}
+
+func newToolOutput(toolName string, outputCopy io.Writer) *toolOutputType {
+	return &toolOutputType{toolName: toolName, outputCopy: outputCopy}
+}
+
+// An io.Writer for a stream of the tool ("stdout" or "stderr"), which handles the output line by line
+type toolLineWriterType struct {
+	toolOutput *toolOutputType
+	stream     string
+	buffer     []byte
+}
+
+// a line longer than this is split
+const maxToolLineLength = 1024 * 1024
+
+func (toolOutput *toolOutputType) newLineWriter(stream string) *toolLineWriterType {
+	return &toolLineWriterType{toolOutput: toolOutput, stream: stream}
+}
+
+func (lineWriter *toolLineWriterType) Write(data []byte) (int, error) {
+	lineWriter.buffer = append(lineWriter.buffer, data...)
+	for {
+		index := bytes.IndexByte(lineWriter.buffer, '\n')
+		lineEnd := index + 1
+		if index < 0 {
+			if len(lineWriter.buffer) < maxToolLineLength {
+				return len(data), nil
+			}
+			index, lineEnd = maxToolLineLength, maxToolLineLength
+		}
+		lineWriter.toolOutput.handleLine(lineWriter.stream, string(lineWriter.buffer[:index]))
+		lineWriter.buffer = lineWriter.buffer[lineEnd:]
+	}
+}
+
+// Handles the rest of the output, which doesn't end with a newline
+func (lineWriter *toolLineWriterType) flush() {
+	if len(lineWriter.buffer) > 0 {
+		lineWriter.toolOutput.handleLine(lineWriter.stream, string(lineWriter.buffer))
+		lineWriter.buffer = nil
+	}
+}
+
+func (toolOutput *toolOutputType) handleLine(stream, line string) {
+	toolOutput.mutex.Lock()
+	defer toolOutput.mutex.Unlock()
+	line = strings.TrimRight(ansiEscapeCodes.ReplaceAllString(line, ""), " \r")
+	writeToolLogLine(toolOutput.toolName, stream, line)
+	if toolOutput.outputCopy != nil {
+		fmt.Fprintln(toolOutput.outputCopy, line)
+	}
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	toolOutput.matchErrorPatterns(line)
+
+	message := toolOutputPrefix(toolOutput.toolName) + line
+	switch toolLineLevel(stream, line) {
+	case log.ErrorLevel:
+		log.Error(message)
+	case log.WarnLevel:
+		log.Warn(message)
+	case log.DebugLevel:
+		log.Debug(message)
+	default:
+		log.Info(message)
+	}
+}
+
+// The prefix of the log messages with the output of a tool, e.g. "[ARS] "
+func toolOutputPrefix(toolName string) string {
+	return "[" + toolName + "] "
+}
+
+// Returns true if a log message is a line of the output of a tool, e.g. to tell it apart from the CLI's own
+// warnings
+func IsToolOutputMessage(message string) bool {
+	for _, toolName := range []string{ToolARS, ToolRepoEditor} {
+		if strings.HasPrefix(message, toolOutputPrefix(toolName)) {
+			return true
+		}
+	}
+	return false
+}
+
+// The level of a line of a tool's output: as the line says, or info for stdout and warning for stderr
+func toolLineLevel(stream, line string) log.Level {
+	switch {
+	case errorLineRegexp.MatchString(line):
+		return log.ErrorLevel
+	case warnLineRegexp.MatchString(line):
+		return log.WarnLevel
+	case debugLineRegexp.MatchString(line):
+		return log.DebugLevel
+	case stream == "stderr":
+		return log.WarnLevel
+	}
+	return log.InfoLevel
+}
+
+func (toolOutput *toolOutputType) matchErrorPatterns(line string) {
+	for _, pattern := range ToolErrorPatterns {
+		if !pattern.Regexp.MatchString(line) {
+			continue
+		}
+		var match *toolErrorMatchType
+		for _, existingMatch := range toolOutput.matches {
+			if existingMatch.Pattern == pattern {
+				match = existingMatch
+			}
+		}
+		if match == nil {
+			match = &toolErrorMatchType{Pattern: pattern}
+			toolOutput.matches = append(toolOutput.matches, match)
+		}
+		match.Count++
+		if len(match.Examples) < maxToolErrorExamples {
+			match.Examples = append(match.Examples, strings.TrimSpace(line))
+		}
+	}
+}
+
+// Summarizes the known error patterns found in the output, "" if there are none
+func (toolOutput *toolOutputType) errorSummary() string {
+	toolOutput.mutex.Lock()
+	defer toolOutput.mutex.Unlock()
+	if len(toolOutput.matches) == 0 {
+		return ""
+	}
+	builder := &strings.Builder{}
+	fmt.Fprintf(builder, "Likely causes, according to the output of the %s:", toolOutput.toolName)
+	for _, match := range toolOutput.matches {
+		fmt.Fprintf(builder, "\n  - %s (%d line(s), e.g. '%s')", match.Pattern.Hint, match.Count,
+			match.Examples[0])
+	}
+	return builder.String()
+}
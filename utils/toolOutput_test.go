@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToolErrorPatterns(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected string // the name of the matching pattern, "" for none
+	}{
+		{`{"message":"401 Unauthorized"}`, "gitlab-unauthorized"},
+		{"Request failed with status code 401", "gitlab-unauthorized"},
+		{"Token is unauthorized for this resource", "gitlab-unauthorized"},
+		{"HTTPError: Response code 403 (Forbidden)", "gitlab-forbidden"},
+		{"HTTP/1.1 403", "gitlab-forbidden"},
+		{`{"message":"404 Project Not Found"}`, "gitlab-not-found"},
+		{"statusCode: 404", "gitlab-not-found"},
+		{"GitbeakerRequestError: Not Found (HTTP 404)", "gitlab-not-found"},
+		{"status: 429", "gitlab-rate-limit"},
+		{"429 Too Many Requests", "gitlab-rate-limit"},
+		{"Rate limit exceeded, retrying in 60s", "gitlab-rate-limit"},
+		{"Variable $StudentName$ not found", "missing-variable"},
+		{"Error: Cannot find module 'typescript'", "missing-node-module"},
+
+		// plain numbers, e.g. IDs and counts, are not HTTP status codes
+		{"Created project 401 in group 404", ""},
+		{"Patched 429 files in 403 ms", ""},
+		{"Generating repository 404 of 429", ""},
+		{"Group ID: 401", ""},
+		{"file not found in cache, downloading", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.line, func(t *testing.T) {
+			matched := []string{}
+			for _, pattern := range ToolErrorPatterns {
+				if pattern.Regexp.MatchString(test.line) {
+					matched = append(matched, pattern.Name)
+				}
+			}
+			expected := []string{}
+			if test.expected != "" {
+				expected = append(expected, test.expected)
+			}
+			if strings.Join(matched, ", ") != strings.Join(expected, ", ") {
+				t.Errorf("expected the patterns [%s] to match, got [%s]", strings.Join(expected, ", "),
+					strings.Join(matched, ", "))
+			}
+		})
+	}
+}
+
+func TestToolErrorSummary(t *testing.T) {
+	toolOutput := newToolOutput(ToolARS, nil)
+	stdout := toolOutput.newLineWriter("stdout")
+	stdout.Write([]byte("Creating repo 404\nRequest failed with status code 403\nstatus code 403 again\n"))
+	stdout.flush()
+
+	summary := toolOutput.errorSummary()
+	expected := "Likely causes, according to the output of the ARS:\n" +
+		"  - GitLab denied access (403) - check that the token's user may access the groups and projects " +
+		"(2 line(s), e.g. 'Request failed with status code 403')"
+	if summary != expected {
+		t.Errorf("expected:\n%s\nactual:\n%s", expected, summary)
+	}
+
+	if summary := newToolOutput(ToolARS, nil).errorSummary(); summary != "" {
+		t.Errorf("expected no summary without matches, got: %s", summary)
+	}
+}
@@ -0,0 +1,37 @@
+//go:build !windows
+
+package utils
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// Runs the tool in its own process group, so that stopping it also stops the processes it started (e.g. node
+// started by npm). When the tool is to be stopped, the group gets the signal that interrupted the run (SIGTERM
+// on a timeout), and SIGKILL if it still runs after toolStopGracePeriod. Returns a function to call after
+// the tool has finished, which kills what is left of a stopped group, so that no orphans keep running.
+func prepareToolProcess(cmd *exec.Cmd) func() {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	var killTimer *time.Timer
+	cmd.Cancel = func() error {
+		processGroup := -cmd.Process.Pid
+		stopSignal := syscall.SIGTERM
+		if interrupt, ok := InterruptSignal().(syscall.Signal); ok {
+			stopSignal = interrupt
+		}
+		killTimer = time.AfterFunc(toolStopGracePeriod, func() {
+			syscall.Kill(processGroup, syscall.SIGKILL)
+		})
+		return syscall.Kill(processGroup, stopSignal)
+	}
+	// the processes of the group may keep the output open, even after the tool itself has been killed
+	cmd.WaitDelay = toolStopGracePeriod + time.Second
+	return func() {
+		if killTimer != nil {
+			killTimer.Stop()
+			syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package utils
+
+import (
+	"os/exec"
+	"time"
+)
+
+// On Windows, the tool is killed when it is to be stopped (the default of exec.CommandContext). Returns a
+// function to call after the tool has finished.
+func prepareToolProcess(cmd *exec.Cmd) func() {
+	cmd.WaitDelay = toolStopGracePeriod + time.Second
+	return func() {}
+}
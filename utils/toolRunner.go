@@ -0,0 +1,194 @@
+package utils
+
+/**
+ * This file contains the configurable invocation of the tools (ARS, Repo Editor): which executable is run with
+ * which arguments, environment, working dir and timeout. By default, a tool is run with 'npm start' in its
+ * repo. The actual start of the process is done by a ToolRunner, which can be replaced, e.g. by a stub.
+ */
+
+import (
+	"context"
+	"fmt"
+	"github.com/apex/log"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The default invocation of a tool
+const (
+	DefaultToolExecutable = "npm"
+	DefaultToolArg        = "start"
+)
+
+// How a tool is run, as configured in the CLI settings. All values may contain environment variables
+// ($VAR or ${VAR}), which are expanded when the tool is run.
+type ToolConfigType struct {
+	Executable string            `json:"executable"` // default "npm"
+	Args       []string          `json:"args"`       // default ["start"] - only if not set at all
+	Env        map[string]string `json:"env"`        // added to the environment of the CLI
+	WorkingDir string            `json:"workingDir"` // absolute, or relative to the tool repo (default)
+	Timeout    string            `json:"timeout"`    // e.g. "30m"; no timeout if empty
+}
+
+// The "tools" section of the CLI settings
+type ToolSettingsType struct {
+	ARS        ToolConfigType `json:"ars"`
+	RepoEditor ToolConfigType `json:"repoEditor"`
+}
+
+// The tool configs by tool name (ToolARS, ToolRepoEditor). Tools without a config use the defaults.
+var ToolConfigs = map[string]ToolConfigType{}
+
+// A tool invocation, with everything resolved
+type ToolInvocationType struct {
+	ToolName   string
+	Executable string
+	Args       []string
+	Env        []string // the complete environment, as in exec.Cmd
+	Dir        string
+	Timeout    time.Duration
+}
+
+// how long a tool gets to stop after it has been asked to, before it is killed
+const toolStopGracePeriod = 10 * time.Second
+
+// Starts a tool and waits until it has finished, writing its output to stdout and stderr. The process is to
+// be stopped when ctx is done.
+type ToolRunner interface {
+	Run(ctx context.Context, invocation *ToolInvocationType, stdout, stderr io.Writer) error
+}
+
+// The ToolRunner used for all tools. Can be replaced, e.g. by a stub that doesn't start any process.
+var DefaultToolRunner ToolRunner = &ProcessToolRunner{}
+
+// The ToolRunner that starts the tool as a process
+type ProcessToolRunner struct{}
+
+func (runner *ProcessToolRunner) Run(ctx context.Context, invocation *ToolInvocationType,
+	stdout, stderr io.Writer) error {
+	log.Debug("utils.ProcessToolRunner.Run() - executable: " + invocation.Executable)
+	cmd := exec.CommandContext(ctx, invocation.Executable, invocation.Args...)
+	cmd.Dir = invocation.Dir
+	cmd.Env = invocation.Env
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	finishToolProcess := prepareToolProcess(cmd)
+	defer finishToolProcess()
+	return cmd.Run()
+}
+
+// The configs of the tools by tool name, as used by ToolConfigs
+func (toolSettings ToolSettingsType) ToolConfigs() map[string]ToolConfigType {
+	return map[string]ToolConfigType{
+		ToolARS:        toolSettings.ARS,
+		ToolRepoEditor: toolSettings.RepoEditor,
+	}
+}
+
+// Returns the config with the values that are set in override replacing its own. The env variables are merged.
+func (toolConfig ToolConfigType) OverriddenBy(override ToolConfigType) ToolConfigType {
+	if override.Executable != "" {
+		toolConfig.Executable = override.Executable
+	}
+	if override.Args != nil {
+		toolConfig.Args = override.Args
+	}
+	if len(override.Env) > 0 {
+		env := make(map[string]string)
+		for envName, value := range toolConfig.Env {
+			env[envName] = value
+		}
+		for envName, value := range override.Env {
+			env[envName] = value
+		}
+		toolConfig.Env = env
+	}
+	if override.WorkingDir != "" {
+		toolConfig.WorkingDir = override.WorkingDir
+	}
+	if override.Timeout != "" {
+		toolConfig.Timeout = override.Timeout
+	}
+	return toolConfig
+}
+
+// Returns the tool configs with each config overridden by the one of the same tool in overrides
+func OverrideToolConfigs(toolConfigs, overrides map[string]ToolConfigType) map[string]ToolConfigType {
+	result := make(map[string]ToolConfigType)
+	for toolName, toolConfig := range toolConfigs {
+		result[toolName] = toolConfig
+	}
+	for toolName, override := range overrides {
+		result[toolName] = result[toolName].OverriddenBy(override)
+	}
+	return result
+}
+
+// Checks the config, e.g. that the timeout can be parsed
+func (toolConfig ToolConfigType) Validate() error {
+	if toolConfig.Timeout == "" {
+		return nil
+	}
+	timeout, err := time.ParseDuration(toolConfig.Timeout)
+	if err != nil || timeout <= 0 {
+		return fmt.Errorf("invalid timeout '%s' - expected a positive duration like '90s' or '30m'",
+			toolConfig.Timeout)
+	}
+	return nil
+}
+
+// Resolves the invocation of a tool from its config: the defaults for everything not configured, and all
+// environment variables expanded. repoDir is the dir of the tool repo.
+func NewToolInvocation(toolName, repoDir string) (*ToolInvocationType, error) {
+	log.Debug("utils.NewToolInvocation() - toolName: " + toolName)
+	toolConfig := ToolConfigs[toolName]
+	if err := toolConfig.Validate(); err != nil {
+		return nil, NewError(ErrInvalidConfig, "the config of the %s: %v", toolName, err)
+	}
+	invocation := &ToolInvocationType{
+		ToolName:   toolName,
+		Executable: DefaultToolExecutable,
+		Args:       []string{DefaultToolArg},
+		Env:        os.Environ(),
+		Dir:        repoDir,
+	}
+	if toolConfig.Executable != "" {
+		invocation.Executable = os.ExpandEnv(toolConfig.Executable)
+	}
+	if toolConfig.Args != nil {
+		invocation.Args = []string{}
+		for _, arg := range toolConfig.Args {
+			invocation.Args = append(invocation.Args, os.ExpandEnv(arg))
+		}
+	}
+	// sorted, so that the invocation is the same in every run
+	envNames := make([]string, 0, len(toolConfig.Env))
+	for envName := range toolConfig.Env {
+		envNames = append(envNames, envName)
+	}
+	sort.Strings(envNames)
+	for _, envName := range envNames {
+		invocation.Env = append(invocation.Env, envName+"="+os.ExpandEnv(toolConfig.Env[envName]))
+	}
+	if toolConfig.WorkingDir != "" {
+		workingDir := os.ExpandEnv(toolConfig.WorkingDir)
+		if !filepath.IsAbs(workingDir) {
+			workingDir = filepath.Join(repoDir, workingDir)
+		}
+		invocation.Dir = workingDir
+	}
+	if toolConfig.Timeout != "" {
+		invocation.Timeout, _ = time.ParseDuration(toolConfig.Timeout)
+	}
+	return invocation, nil
+}
+
+// The command line of the invocation, e.g. "npm start"
+func (invocation *ToolInvocationType) CommandLine() string {
+	return strings.Join(append([]string{invocation.Executable}, invocation.Args...), " ")
+}
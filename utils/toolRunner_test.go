@@ -0,0 +1,270 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A ToolRunner that doesn't start any process, but writes the given output and returns the given error
+type stubToolRunner struct {
+	stdout      string
+	stderr      string
+	err         error
+	waitForStop bool // if true, runs until ctx is done
+	invocations []*ToolInvocationType
+}
+
+func (runner *stubToolRunner) Run(ctx context.Context, invocation *ToolInvocationType,
+	stdout, stderr io.Writer) error {
+	runner.invocations = append(runner.invocations, invocation)
+	io.WriteString(stdout, runner.stdout)
+	io.WriteString(stderr, runner.stderr)
+	if runner.waitForStop {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return runner.err
+}
+
+// Replaces DefaultToolRunner and ToolConfigs for the duration of a test
+func useStubToolRunner(t *testing.T, runner *stubToolRunner, toolConfigs map[string]ToolConfigType) {
+	previousRunner, previousConfigs, previousDryRun := DefaultToolRunner, ToolConfigs, DryRunFlag
+	DefaultToolRunner, ToolConfigs = runner, toolConfigs
+	t.Cleanup(func() {
+		DefaultToolRunner, ToolConfigs, DryRunFlag = previousRunner, previousConfigs, previousDryRun
+	})
+}
+
+func TestNewToolInvocation(t *testing.T) {
+	t.Setenv("DIVEKIT_TEST_TOKEN", "secret")
+	t.Setenv("DIVEKIT_TEST_BIN", "/opt/bin")
+	repoDir := filepath.Join(t.TempDir(), "repo")
+	absoluteDir := filepath.Join(t.TempDir(), "elsewhere")
+	tests := []struct {
+		name               string
+		toolConfig         *ToolConfigType // nil: not configured at all
+		expectedExecutable string
+		expectedArgs       []string
+		expectedEnv        []string
+		expectedDir        string
+		expectedTimeout    time.Duration
+	}{
+		{
+			name:               "defaults without config",
+			expectedExecutable: "npm",
+			expectedArgs:       []string{"start"},
+			expectedDir:        repoDir,
+		},
+		{
+			name:               "defaults with empty config",
+			toolConfig:         &ToolConfigType{},
+			expectedExecutable: "npm",
+			expectedArgs:       []string{"start"},
+			expectedDir:        repoDir,
+		},
+		{
+			name:               "empty args are kept",
+			toolConfig:         &ToolConfigType{Executable: "node", Args: []string{}},
+			expectedExecutable: "node",
+			expectedArgs:       []string{},
+			expectedDir:        repoDir,
+		},
+		{
+			name: "environment variables expanded",
+			toolConfig: &ToolConfigType{
+				Executable: "$DIVEKIT_TEST_BIN/pnpm",
+				Args:       []string{"run", "start", "--token=${DIVEKIT_TEST_TOKEN}"},
+				Env:        map[string]string{"TOKEN": "$DIVEKIT_TEST_TOKEN", "A_MODE": "ci"},
+			},
+			expectedExecutable: "/opt/bin/pnpm",
+			expectedArgs:       []string{"run", "start", "--token=secret"},
+			expectedEnv:        []string{"A_MODE=ci", "TOKEN=secret"},
+			expectedDir:        repoDir,
+		},
+		{
+			name:               "relative working dir",
+			toolConfig:         &ToolConfigType{WorkingDir: "packages/cli"},
+			expectedExecutable: "npm",
+			expectedArgs:       []string{"start"},
+			expectedDir:        filepath.Join(repoDir, "packages", "cli"),
+		},
+		{
+			name:               "absolute working dir",
+			toolConfig:         &ToolConfigType{WorkingDir: absoluteDir},
+			expectedExecutable: "npm",
+			expectedArgs:       []string{"start"},
+			expectedDir:        absoluteDir,
+		},
+		{
+			name:               "timeout",
+			toolConfig:         &ToolConfigType{Timeout: "90s"},
+			expectedExecutable: "npm",
+			expectedArgs:       []string{"start"},
+			expectedDir:        repoDir,
+			expectedTimeout:    90 * time.Second,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			toolConfigs := map[string]ToolConfigType{}
+			if test.toolConfig != nil {
+				toolConfigs[ToolARS] = *test.toolConfig
+			}
+			useStubToolRunner(t, &stubToolRunner{}, toolConfigs)
+
+			invocation, err := NewToolInvocation(ToolARS, repoDir)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if invocation.ToolName != ToolARS {
+				t.Errorf("expected tool name %s, got %s", ToolARS, invocation.ToolName)
+			}
+			if invocation.Executable != test.expectedExecutable {
+				t.Errorf("expected executable %s, got %s", test.expectedExecutable, invocation.Executable)
+			}
+			if !reflect.DeepEqual(invocation.Args, test.expectedArgs) {
+				t.Errorf("expected args %v, got %v", test.expectedArgs, invocation.Args)
+			}
+			environment := os.Environ()
+			if !reflect.DeepEqual(invocation.Env[:len(environment)], environment) {
+				t.Errorf("expected the environment of the CLI to be passed on")
+			}
+			addedEnv := invocation.Env[len(environment):]
+			if strings.Join(addedEnv, " ") != strings.Join(test.expectedEnv, " ") {
+				t.Errorf("expected added environment %v, got %v", test.expectedEnv, addedEnv)
+			}
+			if invocation.Dir != test.expectedDir {
+				t.Errorf("expected dir %s, got %s", test.expectedDir, invocation.Dir)
+			}
+			if invocation.Timeout != test.expectedTimeout {
+				t.Errorf("expected timeout %v, got %v", test.expectedTimeout, invocation.Timeout)
+			}
+		})
+	}
+}
+
+func TestNewToolInvocationWithInvalidTimeout(t *testing.T) {
+	for _, timeout := range []string{"soon", "-5m", "0s"} {
+		useStubToolRunner(t, &stubToolRunner{}, map[string]ToolConfigType{ToolRepoEditor: {Timeout: timeout}})
+		_, err := NewToolInvocation(ToolRepoEditor, t.TempDir())
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("timeout %s: expected an error of class %s, got %v", timeout, ErrInvalidConfig.Name, err)
+		}
+	}
+}
+
+func TestRunToolWithStubRunner(t *testing.T) {
+	runner := &stubToolRunner{stdout: "Generating 3 repositories\nDone", stderr: "npm WARN deprecated\n"}
+	useStubToolRunner(t, runner, map[string]ToolConfigType{ToolARS: {Args: []string{"run", "generate"}}})
+	repoDir := t.TempDir()
+	outputCopy := &strings.Builder{}
+
+	err := RunToolWithOutputCopy(ToolARS, repoDir, "Generating", outputCopy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(runner.invocations) != 1 {
+		t.Fatalf("expected the tool to be run once, got %d runs", len(runner.invocations))
+	}
+	if commandLine := runner.invocations[0].CommandLine(); commandLine != "npm run generate" {
+		t.Errorf("expected 'npm run generate', got '%s'", commandLine)
+	}
+	if runner.invocations[0].Dir != repoDir {
+		t.Errorf("expected the tool to run in %s, got %s", repoDir, runner.invocations[0].Dir)
+	}
+	// stdout and stderr are written one after the other by the stub, the last line without a newline
+	expectedOutput := "Generating 3 repositories\nnpm WARN deprecated\nDone\n"
+	if outputCopy.String() != expectedOutput {
+		t.Errorf("expected output copy:\n%s\ngot:\n%s", expectedOutput, outputCopy.String())
+	}
+}
+
+func TestRunToolInDryRun(t *testing.T) {
+	runner := &stubToolRunner{}
+	useStubToolRunner(t, runner, map[string]ToolConfigType{})
+	DryRunFlag = true
+
+	if err := RunTool(ToolRepoEditor, t.TempDir(), "Patching"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(runner.invocations) != 0 {
+		t.Errorf("expected RunTool not to run the tool in a dry run")
+	}
+	if err := RunToolAlways(ToolARS, t.TempDir(), "Generating"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(runner.invocations) != 1 {
+		t.Errorf("expected RunToolAlways to run the tool in a dry run")
+	}
+}
+
+func TestRunToolFailure(t *testing.T) {
+	runner := &stubToolRunner{
+		stdout: "Patching repo 4711\nHTTP 403 Forbidden for project 4711\n",
+		err:    errors.New("exit status 1"),
+	}
+	useStubToolRunner(t, runner, map[string]ToolConfigType{})
+
+	err := RunToolAlways(ToolRepoEditor, t.TempDir(), "Patching")
+	if !errors.Is(err, ErrToolFailed) {
+		t.Fatalf("expected an error of class %s, got %v", ErrToolFailed.Name, err)
+	}
+	if ExitCode(err) != ErrToolFailed.ExitCode {
+		t.Errorf("expected exit code %d, got %d", ErrToolFailed.ExitCode, ExitCode(err))
+	}
+	for _, expected := range []string{"'npm start' of the Repo Editor", "exit status 1", "GitLab denied access (403)"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected the error to contain '%s':\n%v", expected, err)
+		}
+	}
+}
+
+func TestRunToolTimeout(t *testing.T) {
+	runner := &stubToolRunner{waitForStop: true}
+	useStubToolRunner(t, runner, map[string]ToolConfigType{ToolARS: {Timeout: "10ms"}})
+
+	err := RunToolAlways(ToolARS, t.TempDir(), "Generating")
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected an error of class %s, got %v", ErrTimeout.Name, err)
+	}
+	expected := fmt.Sprintf("the %s didn't finish within its timeout of 10ms", ToolARS)
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected the error to contain '%s', got: %v", expected, err)
+	}
+}
+
+func TestOverrideToolConfigs(t *testing.T) {
+	machineConfigs := map[string]ToolConfigType{
+		ToolARS: {Executable: "pnpm", Args: []string{"start"}, Timeout: "30m",
+			Env: map[string]string{"NODE_OPTIONS": "--max-old-space-size=8192", "MODE": "lab"}},
+		ToolRepoEditor: {Executable: "npm"},
+	}
+	originConfigs := map[string]ToolConfigType{
+		ToolARS:        {Env: map[string]string{"MODE": "ci"}, Timeout: "1h"},
+		ToolRepoEditor: {Args: []string{}},
+	}
+	expected := map[string]ToolConfigType{
+		ToolARS: {Executable: "pnpm", Args: []string{"start"}, Timeout: "1h",
+			Env: map[string]string{"NODE_OPTIONS": "--max-old-space-size=8192", "MODE": "ci"}},
+		ToolRepoEditor: {Executable: "npm", Args: []string{}},
+	}
+	actual := OverrideToolConfigs(machineConfigs, originConfigs)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+	if machineConfigs[ToolARS].Env["MODE"] != "lab" {
+		t.Errorf("expected the overridden configs to be unchanged")
+	}
+	actual = OverrideToolConfigs(machineConfigs, map[string]ToolConfigType{})
+	if !reflect.DeepEqual(actual, machineConfigs) {
+		t.Errorf("expected the configs without overrides to be unchanged, got %+v", actual)
+	}
+}